@@ -0,0 +1,246 @@
+// Command simulate plays games of Arrows Away without a window, using a
+// simple bot for a single player, and prints how each one ended. It can also
+// record the games it plays and check that a replay still plays out the way
+// it was recorded.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/markrzasa/arrowsaway/game"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/level"
+	"github.com/markrzasa/arrowsaway/replay"
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+const (
+	width  int = 1000
+	height int = 1000
+)
+
+var (
+	numEnemies int
+	levelsDir  string
+	enemyTypes = sprites.DefaultEnemyTypes()
+	bossTypes  sprites.BossTypes
+
+	// with measureAllocs set every update is timed for heap allocations,
+	// which is slow but shows whether the simulation allocates as it runs
+	measureAllocs bool
+	updates       uint64
+	allocations   uint64
+)
+
+func levels() []*level.Level {
+	levels, err := level.LoadWithDefaults(levelsDir, enemyTypes, bossTypes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if numEnemies > 0 {
+		for _, l := range levels {
+			l.SetEnemiesPerStage(numEnemies)
+		}
+	}
+	return levels
+}
+
+func update(w *game.World, intents map[int]input.Intent) {
+	if !measureAllocs {
+		w.Update(intents)
+		return
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	w.Update(intents)
+	runtime.ReadMemStats(&after)
+	updates = updates + 1
+	allocations = allocations + (after.Mallocs - before.Mallocs)
+}
+
+// bot aims at the nearest living enemy, fires constantly and backs away
+// from anything that gets too close.
+func bot(w *game.World, p *game.Player) input.Intent {
+	hero := p.Hero.Position()
+	var nearest geom.Vector
+	distance := -1.0
+	for _, e := range w.Enemies().All() {
+		if !e.IsAlive() {
+			continue
+		}
+		d := geom.Vector{X: float64(e.Sprite.X), Y: float64(e.Sprite.Y)}.Sub(hero)
+		if distance < 0 || d.Length() < distance {
+			nearest = d
+			distance = d.Length()
+		}
+	}
+
+	intent := input.Intent{Confirm: true}
+	if distance < 0 {
+		return intent
+	}
+	intent.Aim = nearest.Normalize()
+	intent.Fire = true
+	if distance < 150 {
+		intent.Move = nearest.Normalize().Scale(-1)
+	}
+	return intent
+}
+
+func levelNames(levels []*level.Level) []string {
+	names := []string{}
+	for _, l := range levels {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+func simulate(seed int64, maxTicks int, record io.Writer) (game.State, *game.World, error) {
+	w := game.NewWorld(width, height, seed, true, levels())
+	var recorder *replay.Recorder
+	if record != nil {
+		var err error
+		recorder, err = replay.NewRecorder(record, replay.Header{
+			Seed:      seed,
+			FixedSeed: true,
+			Width:     width,
+			Height:    height,
+			Levels:    levelNames(w.Levels()),
+		})
+		if err != nil {
+			return w.State(), w, err
+		}
+	}
+
+	p := w.AddPlayer()
+	f := &replay.Frame{
+		Events:  []replay.Event{{Kind: replay.Join, Player: p.Id}},
+		Intents: map[int]input.Intent{},
+	}
+	for i := 0; i < maxTicks; i++ {
+		f.Intents[p.Id] = bot(w, p)
+		update(w, f.Intents)
+		if recorder != nil {
+			if err := recorder.Record(f, w); err != nil {
+				return w.State(), w, err
+			}
+		}
+		f.Events = nil
+		if w.State() == game.GameOver || w.State() == game.Winner {
+			break
+		}
+	}
+	if recorder != nil {
+		return w.State(), w, recorder.Close()
+	}
+	return w.State(), w, nil
+}
+
+// verify plays back the replay at path and reports the first place it no
+// longer matches the recording.
+func verify(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := replay.NewReader(f)
+	if err != nil {
+		return err
+	}
+	h := r.Header()
+	levels := levels()
+	if fmt.Sprint(h.Levels) != fmt.Sprint(levelNames(levels)) {
+		return fmt.Errorf("replay was recorded with levels %v", h.Levels)
+	}
+	w := game.NewWorld(h.Width, h.Height, h.Seed, h.FixedSeed, levels)
+	for n := int64(1); ; n++ {
+		frame, err := r.Next()
+		if err == io.EOF {
+			fmt.Printf("%s: %d frames match, %s after %d ticks\n", path, n-1, w.State(), w.Tick())
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := replay.Apply(w, frame); err != nil {
+			return err
+		}
+		if err := replay.Check(w, n, frame); err != nil {
+			return err
+		}
+	}
+}
+
+func writer(f *os.File) io.Writer {
+	if f == nil {
+		return nil
+	}
+	return f
+}
+
+func main() {
+	games := flag.Int("games", 100, "number of games to play")
+	seed := flag.Int64("seed", 1, "seed of the first game, later games use the following seeds")
+	maxTicks := flag.Int("ticks", 60*60*10, "most updates to run per game")
+	record := flag.String("record", "", "directory to write a replay of every game to")
+	flag.IntVar(&numEnemies, "enemies", 0, "enemies in each stage, 0 for as many as the levels say")
+	flag.StringVar(&levelsDir, "levels", "", "directory of JSON level files to play after the built in levels, replacing any of the same name")
+	flag.BoolVar(&measureAllocs, "allocs", false, "report heap allocations per update")
+	enemyTypesFile := flag.String("enemy-types", "", "JSON file of extra enemy types")
+	flag.Parse()
+
+	if *enemyTypesFile != "" {
+		if err := enemyTypes.LoadFile(*enemyTypesFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	bossTypes = sprites.DefaultBossTypes(enemyTypes)
+
+	if flag.NArg() > 0 {
+		failed := false
+		for _, path := range flag.Args() {
+			if err := verify(path); err != nil {
+				fmt.Printf("%s: %v\n", path, err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	results := map[game.State]int{}
+	for i := 0; i < *games; i++ {
+		var out *os.File
+		if *record != "" {
+			var err error
+			out, err = os.Create(filepath.Join(*record, fmt.Sprintf("%d.replay", *seed+int64(i))))
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		state, w, err := simulate(*seed+int64(i), *maxTicks, writer(out))
+		if out != nil {
+			out.Close()
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		results[state] = results[state] + 1
+		fmt.Printf("seed %d: %s after %d ticks, level %d, score %d\n",
+			w.Seed(), state, w.Tick(), w.LevelIndex()+1, w.Players()[0].Score)
+	}
+	fmt.Printf("won %d, lost %d, unfinished %d\n",
+		results[game.Winner], results[game.GameOver], *games-results[game.Winner]-results[game.GameOver])
+	if measureAllocs && updates > 0 {
+		fmt.Printf("%.2f allocations per update over %d updates\n", float64(allocations)/float64(updates), updates)
+	}
+}
@@ -0,0 +1,106 @@
+// Package entity hands out the IDs game objects are stored and looked up by.
+package entity
+
+// ID refers to an entity in a Store. The low 32 bits are the slot the entity
+// is kept in and the high 32 bits are its generation, a serial number that
+// goes up with every entity the Store creates. IDs of newer entities always
+// compare greater, and an ID kept after its entity was removed no longer
+// matches the generation of its slot.
+type ID uint64
+
+const (
+	None ID = 0
+)
+
+func newID(slot int, generation uint32) ID {
+	return ID(uint64(generation)<<32 | uint64(uint32(slot)))
+}
+
+func (id ID) Slot() int {
+	return int(uint32(id))
+}
+
+func (id ID) Generation() uint32 {
+	return uint32(id >> 32)
+}
+
+// Store allocates IDs and remembers which ones are live. Slots of removed
+// entities are reused, so callers can keep their entities in a slice indexed
+// by ID.Slot.
+type Store struct {
+	generations []uint32
+	free        []int
+	order       []ID
+	removed     int
+	next        uint32
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Add returns the ID for a new entity.
+func (s *Store) Add() ID {
+	s.next = s.next + 1
+	slot := len(s.generations)
+	if n := len(s.free); n > 0 {
+		slot = s.free[n-1]
+		s.free = s.free[:n-1]
+	} else {
+		s.generations = append(s.generations, 0)
+	}
+	s.generations[slot] = s.next
+	id := newID(slot, s.next)
+	s.order = append(s.order, id)
+	return id
+}
+
+// Contains reports whether id refers to an entity that has not been removed.
+func (s *Store) Contains(id ID) bool {
+	slot := id.Slot()
+	return id != None && slot < len(s.generations) && s.generations[slot] == id.Generation()
+}
+
+// Remove frees the slot used by id and reports whether it was live.
+func (s *Store) Remove(id ID) bool {
+	if !s.Contains(id) {
+		return false
+	}
+	s.generations[id.Slot()] = 0
+	s.free = append(s.free, id.Slot())
+	s.removed = s.removed + 1
+	return true
+}
+
+func (s *Store) Len() int {
+	return len(s.order) - s.removed
+}
+
+// IDs returns the live IDs oldest first. Entities may be added and removed
+// while ranging over the result, but it is only valid until the next call to
+// IDs or Clear.
+func (s *Store) IDs() []ID {
+	if s.removed > 0 {
+		live := s.order[:0]
+		for _, id := range s.order {
+			if s.Contains(id) {
+				live = append(live, id)
+			}
+		}
+		for i := len(live); i < len(s.order); i++ {
+			s.order[i] = None
+		}
+		s.order = live
+		s.removed = 0
+	}
+	return s.order
+}
+
+// Clear removes every entity. Generations keep counting up so IDs from before
+// the Clear stay stale.
+func (s *Store) Clear() {
+	s.generations = s.generations[:0]
+	s.free = s.free[:0]
+	s.order = s.order[:0]
+	s.removed = 0
+}
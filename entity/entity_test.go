@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReuseSlot(t *testing.T) {
+	s := NewStore()
+	a := s.Add()
+	b := s.Add()
+	if !s.Contains(a) || !s.Contains(b) {
+		t.Fatalf("live IDs %v and %v not found", a, b)
+	}
+	if !s.Remove(a) {
+		t.Fatalf("removing %v reported it was not live", a)
+	}
+
+	c := s.Add()
+	if c.Slot() != a.Slot() {
+		t.Errorf("new ID in slot %d, want the freed slot %d", c.Slot(), a.Slot())
+	}
+	if c.Generation() <= a.Generation() || c <= b {
+		t.Errorf("new ID %x does not come after %x and %x", c, a, b)
+	}
+	if s.Contains(a) {
+		t.Errorf("stale ID %x still found after its slot was reused", a)
+	}
+	if !s.Contains(b) || !s.Contains(c) {
+		t.Errorf("live IDs %x and %x not found", b, c)
+	}
+	if s.Remove(a) {
+		t.Errorf("removing stale ID %x reported it was live", a)
+	}
+	if !s.Contains(c) {
+		t.Errorf("removing a stale ID removed %x from the same slot", c)
+	}
+}
+
+func TestContains(t *testing.T) {
+	s := NewStore()
+	a := s.Add()
+	tests := []struct {
+		name string
+		id   ID
+		want bool
+	}{
+		{"live", a, true},
+		{"none", None, false},
+		{"slot never used", newID(5, a.Generation()), false},
+		{"wrong generation", newID(a.Slot(), a.Generation()+1), false},
+	}
+	for _, tt := range tests {
+		if got := s.Contains(tt.id); got != tt.want {
+			t.Errorf("%s: Contains(%x) = %v, want %v", tt.name, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestIDs(t *testing.T) {
+	s := NewStore()
+	ids := []ID{s.Add(), s.Add(), s.Add(), s.Add()}
+	s.Remove(ids[1])
+	s.Remove(ids[2])
+	e := s.Add()
+	want := []ID{ids[0], ids[3], e}
+	if got := s.IDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("IDs() = %x, want %x", got, want)
+	}
+	if s.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", s.Len())
+	}
+}
+
+func TestClearLeavesIDsStale(t *testing.T) {
+	s := NewStore()
+	a := s.Add()
+	s.Clear()
+	b := s.Add()
+	if b.Slot() != a.Slot() {
+		t.Fatalf("first ID after Clear in slot %d, want %d", b.Slot(), a.Slot())
+	}
+	if s.Contains(a) {
+		t.Errorf("ID %x from before Clear still found", a)
+	}
+	if !s.Contains(b) || s.Len() != 1 {
+		t.Errorf("ID %x added after Clear not found", b)
+	}
+}
@@ -0,0 +1,69 @@
+package game
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+type checksum struct {
+	buf [8]byte
+}
+
+func (c *checksum) hash(values ...uint64) uint64 {
+	h := fnv.New64a()
+	for _, v := range values {
+		for i := range c.buf {
+			c.buf[i] = byte(v >> (8 * i))
+		}
+		h.Write(c.buf[:])
+	}
+	return h.Sum64()
+}
+
+func bits(f float64) uint64 {
+	return math.Float64bits(f)
+}
+
+func flag(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Checksum summarizes the state of the world so two runs can be compared
+// without keeping a copy of either.
+func (w *World) Checksum() uint64 {
+	c := &checksum{}
+	sum := c.hash(uint64(w.state), uint64(w.tick), uint64(w.seed), uint64(w.width), uint64(w.height),
+		uint64(w.levelIndex), uint64(w.kills))
+	for _, l := range w.levels {
+		sum = c.hash(sum, uint64(l.GetStage()), uint64(l.Wave()))
+	}
+	for _, p := range w.players {
+		h := p.Hero
+		sum = c.hash(sum, uint64(p.Id), uint64(p.Score), uint64(p.Lives), uint64(p.StartWeapon),
+			uint64(h.Sprite.X), uint64(h.Sprite.Y), uint64(h.Weapon.Kind))
+	}
+	for _, p := range w.pickups {
+		sum = c.hash(sum, uint64(p.Weapon), uint64(p.Sprite.X), uint64(p.Sprite.Y))
+	}
+	for _, id := range w.enemies.IDs() {
+		e := w.enemies.Get(id)
+		sum = c.hash(sum, uint64(id), uint64(e.Sprite.X), uint64(e.Sprite.Y), uint64(e.Sprite.Frame),
+			bits(e.Health()), flag(e.IsAlive()), flag(e.IsBuried()))
+		if e.Boss != nil {
+			sum = c.hash(sum, uint64(e.Boss.Phase()), flag(e.Boss.IsCharging()))
+		}
+	}
+	for _, arrows := range []*sprites.ArrowStore{w.arrows, w.enemyArrows} {
+		for _, id := range arrows.IDs() {
+			a := arrows.Get(id)
+			sum = c.hash(sum, uint64(id), uint64(a.Owner), uint64(a.Kind), bits(a.Position.X), bits(a.Position.Y),
+				bits(a.Direction.X), bits(a.Direction.Y))
+		}
+	}
+	return sum
+}
@@ -0,0 +1,302 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/markrzasa/arrowsaway/entity"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+const (
+	killsPerPickup int = 15
+
+	// enemies closer than separationRadius push each other apart, up to
+	// separationWeight times their speed when right on top of each other
+	separationRadius float64 = 28
+	separationWeight float64 = 1.5
+)
+
+func (w *World) scoreHit(owner *Player, e *sprites.Enemy) {
+	if owner != nil {
+		owner.Score = owner.Score + e.Type.Score
+	}
+	if !e.IsAlive() {
+		w.kills = w.kills + 1
+		if w.kills%killsPerPickup == 0 {
+			drop := (w.kills / killsPerPickup) - 1
+			kind := sprites.WeaponKind(1 + (drop % int(sprites.NumWeaponKinds-1)))
+			w.pickups = append(w.pickups, sprites.NewPickup(e.Sprite.X, e.Sprite.Y, kind))
+		}
+	}
+}
+
+// indexEnemies rebuilds the broadphase used by every enemy collision query
+// during a tick.
+func (w *World) indexEnemies() {
+	w.enemyGrid.Clear()
+	w.enemyList = w.enemyList[:0]
+	w.enemyIds = w.enemyIds[:0]
+	for _, id := range w.enemies.IDs() {
+		e := w.enemies.Get(id)
+		w.enemyGrid.Insert(len(w.enemyList), e.Sprite.Rect())
+		w.enemyList = append(w.enemyList, e)
+		w.enemyIds = append(w.enemyIds, id)
+	}
+}
+
+// indexEnemyArrows rebuilds the broadphase the heroes are checked against
+// for enemy arrows during a tick.
+func (w *World) indexEnemyArrows() {
+	w.enemyArrowGrid.Clear()
+	w.enemyArrowIds = w.enemyArrowIds[:0]
+	for _, id := range w.enemyArrows.IDs() {
+		a := w.enemyArrows.Get(id)
+		w.enemyArrowGrid.Insert(len(w.enemyArrowIds), geom.RectAround(a.Previous, a.Position))
+		w.enemyArrowIds = append(w.enemyArrowIds, id)
+	}
+}
+
+func (w *World) explode(a *sprites.Arrow, center geom.Vector, owner *Player, shooter *sprites.Sprite) {
+	w.nearby = w.enemyGrid.QueryRadius(center, a.BlastRadius, w.nearby[:0])
+	for _, i := range w.nearby {
+		e := w.enemyList[i]
+		if e.IsAlive() && !a.HasStruck(w.enemyIds[i]) && e.InBlast(center, a.BlastRadius) {
+			e.Shot(a.Damage, shooter)
+			w.scoreHit(owner, e)
+		}
+	}
+}
+
+func (w *World) shooter(a *sprites.Arrow) (*Player, *sprites.Sprite) {
+	owner := w.Player(a.Owner)
+	if owner == nil {
+		return nil, &a.Sprite
+	}
+	return owner, owner.Hero.Sprite
+}
+
+type arrowHit struct {
+	t     float64
+	id    entity.ID
+	enemy *sprites.Enemy
+}
+
+type byDistance []arrowHit
+
+func (h byDistance) Len() int           { return len(h) }
+func (h byDistance) Less(i, j int) bool { return h[i].t < h[j].t }
+func (h byDistance) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// hitEnemy sweeps the arrow along the path it took this update and applies
+// hits in the order the arrow reached the enemies, stopping once the arrow is
+// used up.
+func (w *World) hitEnemy(a *sprites.Arrow) bool {
+	w.hits = w.hits[:0]
+	w.nearby = w.enemyGrid.QuerySegment(a.Previous, a.Position, w.nearby[:0])
+	for _, i := range w.nearby {
+		id := w.enemyIds[i]
+		if a.HasStruck(id) {
+			continue
+		}
+		if t, ok := w.enemyList[i].Sweep(a); ok {
+			w.hits = append(w.hits, arrowHit{t: t, id: id, enemy: w.enemyList[i]})
+		}
+	}
+	if len(w.hits) > 1 {
+		sort.Stable(byDistance(w.hits))
+	}
+
+	owner, shooter := w.shooter(a)
+	for _, h := range w.hits {
+		if h.enemy.Hit(a, shooter) {
+			w.scoreHit(owner, h.enemy)
+		}
+		if a.Strike(h.id) {
+			if a.BlastRadius > 0 {
+				w.explode(a, a.PointAt(h.t), owner, shooter)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) updatePickups() {
+	for _, p := range w.LivingPlayers() {
+		for i := 0; i < len(w.pickups); i++ {
+			if w.pickups[i].Sprite.Intersect(p.Hero.Sprite) {
+				p.Hero.Weapon = sprites.NewWeapon(w.pickups[i].Weapon)
+				w.pickups = append(w.pickups[:i], w.pickups[i+1:]...)
+				i = i - 1
+			}
+		}
+	}
+}
+
+func (w *World) touchesEnemy(p *Player) bool {
+	w.nearby = w.enemyGrid.Query(p.Hero.Sprite.Rect(), w.nearby[:0])
+	for _, i := range w.nearby {
+		e := w.enemyList[i]
+		if e.IsAlive() && e.Sprite.Intersect(p.Hero.Sprite) {
+			return true
+		}
+	}
+	return false
+}
+
+// shotByEnemy sweeps the enemy arrows along the path they took during their
+// last update and removes the first one that hit the player.
+func (w *World) shotByEnemy(p *Player) bool {
+	shape := p.Hero.Sprite.Shape()
+	w.nearby = w.enemyArrowGrid.Query(shape.Bounds(), w.nearby[:0])
+	for _, i := range w.nearby {
+		id := w.enemyArrowIds[i]
+		// the arrow may already have hit another hero this tick
+		a := w.enemyArrows.Get(id)
+		if a == nil {
+			continue
+		}
+		if _, ok := geom.SegmentShape(a.Previous, a.Position, shape); ok {
+			w.enemyArrows.Remove(id)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) updateHeroes() {
+	hit := false
+	for _, p := range w.LivingPlayers() {
+		if w.touchesEnemy(p) || w.shotByEnemy(p) {
+			p.Lives = p.Lives - 1
+			hit = true
+		}
+	}
+	if hit {
+		if len(w.LivingPlayers()) == 0 {
+			w.state = GameOver
+		} else {
+			w.state = LostLife
+		}
+	}
+}
+
+func (w *World) updateArrows() {
+	for _, p := range w.LivingPlayers() {
+		weapon := p.Hero.Weapon
+		if p.intent.Fire && weapon.CanFire(w.tick) {
+			weapon.Fire(p.Id, p.Hero.Position(), p.intent.Aim, w.tick, w.arrows)
+		}
+	}
+
+	for _, id := range w.arrows.IDs() {
+		a := w.arrows.Get(id)
+		if w.hitEnemy(a) {
+			w.arrows.Remove(id)
+		} else if a.IsSpent() {
+			if a.BlastRadius > 0 {
+				owner, shooter := w.shooter(a)
+				w.explode(a, a.Position, owner, shooter)
+			}
+			w.arrows.Remove(id)
+		} else if a.IsOffScreen(w.width, w.height) {
+			w.arrows.Remove(id)
+		}
+	}
+
+	for _, a := range w.arrows.All() {
+		a.Update()
+		w.stopAtWalls(a)
+	}
+}
+
+// stopAtWalls stops an arrow that flew into a wall where it hit.
+func (w *World) stopAtWalls(a *sprites.Arrow) {
+	if t, ok := w.walls().Segment(a.Previous, a.Position); ok {
+		a.Stop(t)
+	}
+}
+
+func (w *World) nearestPlayer(position geom.Vector) *Player {
+	var nearest *Player
+	nearestDistance := 0.0
+	for _, p := range w.players {
+		if !p.IsAlive() {
+			continue
+		}
+		d := p.Hero.Position().Sub(position).Length()
+		if nearest == nil || d < nearestDistance {
+			nearest = p
+			nearestDistance = d
+		}
+	}
+	return nearest
+}
+
+// separation returns how strongly the living enemies around enemy i push it
+// away so crowds spread out instead of piling up on the hero.
+func (w *World) separation(i int) geom.Vector {
+	e := w.enemyList[i]
+	push := geom.Vector{}
+	w.nearby = w.enemyGrid.QueryRadius(e.Position(), separationRadius, w.nearby[:0])
+	for _, j := range w.nearby {
+		o := w.enemyList[j]
+		if j == i || !o.IsAlive() {
+			continue
+		}
+		away := e.Position().Sub(o.Position())
+		d := away.Length()
+		if d >= separationRadius {
+			continue
+		}
+		if d == 0 {
+			// enemies on the same spot split in directions picked by
+			// their order so the result does not depend on chance
+			away = geom.FromAngle(float64(i))
+		}
+		push = push.Add(away.Normalize().Scale(1 - (d / separationRadius)))
+	}
+	if push.Length() > 1 {
+		push = push.Normalize()
+	}
+	return push.Scale(separationWeight)
+}
+
+func (w *World) updateEnemies() {
+	heroes := w.livingHeroes()
+	w.paths.Update(w.walls(), w.heroPositions())
+	for i, e := range w.enemyList {
+		if e.IsBuried() {
+			w.enemies.Remove(w.enemyIds[i])
+			continue
+		}
+		push := geom.Vector{}
+		if e.IsAlive() {
+			push = w.separation(i)
+		}
+		e.Update(w.width, w.height, heroes, push, w.walls(), &w.paths)
+		if e.Boss != nil {
+			if p := w.nearestPlayer(e.Position()); p != nil {
+				e.Boss.Attack(e, p.Hero.Position(), w.enemyArrows, w.enemies, w.walls(), w.rng)
+			}
+		} else if e.Type.IsRanged() {
+			if p := w.nearestPlayer(e.Position()); p != nil {
+				e.Fire(p.Hero.Position(), p.Hero.Velocity, w.enemyArrows)
+			}
+		}
+	}
+}
+
+func (w *World) updateEnemyArrows() {
+	for _, id := range w.enemyArrows.IDs() {
+		a := w.enemyArrows.Get(id)
+		if a.IsSpent() || a.IsOffScreen(w.width, w.height) {
+			w.enemyArrows.Remove(id)
+		}
+	}
+	for _, a := range w.enemyArrows.All() {
+		a.Update()
+		w.stopAtWalls(a)
+	}
+}
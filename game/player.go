@@ -0,0 +1,202 @@
+package game
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/level"
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+const (
+	startingLives int = 3
+
+	// heroes joining mid stage look for somewhere to start on up to
+	// joinRings rings of spots joinStep apart
+	joinStep  float64 = 40
+	joinRings int     = 12
+)
+
+var playerTints = []color.Color{
+	color.RGBA{0xff, 0xff, 0xff, 0xff},
+	color.RGBA{0xff, 0x80, 0x80, 0xff},
+	color.RGBA{0x80, 0xa0, 0xff, 0xff},
+	color.RGBA{0x80, 0xff, 0x80, 0xff},
+	color.RGBA{0xff, 0xe0, 0x60, 0xff},
+	color.RGBA{0xe0, 0x80, 0xff, 0xff},
+}
+
+type Player struct {
+	Id   int
+	Hero *sprites.Hero
+
+	StartWeapon sprites.WeaponKind
+
+	Score int64
+	Lives int
+
+	intent          input.Intent
+	selectDirection int
+}
+
+func newPlayer(id int) *Player {
+	return &Player{
+		Id:          id,
+		Hero:        sprites.NewHero(images.Hero, playerTints[id%len(playerTints)]),
+		StartWeapon: sprites.Bow,
+		Score:       0,
+		Lives:       startingLives,
+	}
+}
+
+func (p *Player) IsAlive() bool {
+	return p.Lives > 0
+}
+
+func (p *Player) reset() {
+	p.Lives = startingLives
+	p.Score = 0
+	p.Hero.Weapon = sprites.NewWeapon(p.StartWeapon)
+}
+
+func (p *Player) selectWeapon() {
+	direction := 0
+	if p.intent.Move.X > 0.5 {
+		direction = 1
+	} else if p.intent.Move.X < -0.5 {
+		direction = -1
+	}
+	if direction != 0 && direction != p.selectDirection {
+		p.StartWeapon = (p.StartWeapon + sprites.WeaponKind(direction) + sprites.NumWeaponKinds) % sprites.NumWeaponKinds
+		p.Hero.Weapon = sprites.NewWeapon(p.StartWeapon)
+	}
+	p.selectDirection = direction
+}
+
+// AddPlayer spawns a hero for a new player. Players joining mid stage are
+// dropped as near the middle of the arena as they can be without starting
+// in a wall or next to an enemy; otherwise everybody is lined up again.
+func (w *World) AddPlayer() *Player {
+	p := newPlayer(w.nextPlayerId)
+	w.nextPlayerId = w.nextPlayerId + 1
+	w.players = append(w.players, p)
+	if w.state == Running || w.state == Paused {
+		spot := w.joinSpot()
+		p.Hero.Sprite.X = int(spot.X)
+		p.Hero.Sprite.Y = int(spot.Y)
+	} else {
+		w.startHeroes()
+	}
+	return p
+}
+
+func (w *World) RemovePlayer(id int) {
+	for i, p := range w.players {
+		if p.Id == id {
+			w.players = append(w.players[:i], w.players[i+1:]...)
+			break
+		}
+	}
+}
+
+func (w *World) Player(id int) *Player {
+	for _, p := range w.players {
+		if p.Id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (w *World) Players() []*Player {
+	return w.players
+}
+
+// LivingPlayers returns the players with lives left. The slice is reused by
+// the next call.
+func (w *World) LivingPlayers() []*Player {
+	w.living = w.living[:0]
+	for _, p := range w.players {
+		if p.IsAlive() {
+			w.living = append(w.living, p)
+		}
+	}
+	return w.living
+}
+
+func (w *World) livingHeroes() []*sprites.Sprite {
+	w.heroes = w.heroes[:0]
+	for _, p := range w.players {
+		if p.IsAlive() {
+			w.heroes = append(w.heroes, p.Hero.Sprite)
+		}
+	}
+	return w.heroes
+}
+
+// heroPositions returns where the living heroes are, or the middle of the
+// arena when there are none yet, so enemies can be kept away from them.
+func (w *World) heroPositions() []geom.Vector {
+	w.positions = w.positions[:0]
+	for _, p := range w.LivingPlayers() {
+		w.positions = append(w.positions, p.Hero.Position())
+	}
+	if len(w.positions) == 0 {
+		w.positions = append(w.positions, geom.Vector{X: float64(w.width) / 2, Y: float64(w.height) / 2})
+	}
+	return w.positions
+}
+
+// clearOfEnemies reports whether p is at least SafeRadius from every
+// living enemy.
+func (w *World) clearOfEnemies(p geom.Vector) bool {
+	for _, e := range w.enemies.All() {
+		if e.IsAlive() && e.Position().Sub(p).Length() < level.SafeRadius {
+			return false
+		}
+	}
+	return true
+}
+
+// joinSpot returns where a hero joining mid stage starts: the first spot
+// on open floor and clear of the enemies found on rings of spots joinStep
+// apart around the middle of the arena, or the middle when there is none.
+func (w *World) joinSpot() geom.Vector {
+	middle := w.walls().NearestOpen(geom.Vector{X: float64(w.width) / 2, Y: float64(w.height) / 2}, anywhere)
+	for ring := 0; ring <= joinRings; ring++ {
+		spots := 1 + (8 * ring)
+		for i := 0; i < spots; i++ {
+			spot := middle.Add(geom.FromAngle(2 * math.Pi * float64(i) / float64(spots)).Scale(float64(ring) * joinStep))
+			if spot.X < 0 || spot.Y < 0 || spot.X > float64(w.width) || spot.Y > float64(w.height) {
+				continue
+			}
+			spot = w.walls().NearestOpen(spot, anywhere)
+			if w.clearOfEnemies(spot) {
+				return spot
+			}
+		}
+	}
+	return middle
+}
+
+// anywhere lets a hero start on any open tile.
+func anywhere(geom.Vector) bool {
+	return true
+}
+
+// startHeroes lines the living heroes up side by side in the middle of the
+// arena, moving any that would start in a wall to the nearest open tile.
+func (w *World) startHeroes() {
+	living := w.LivingPlayers()
+	for i, p := range living {
+		p.Hero.Sprite.Scale(1)
+		p.Hero.Sprite.Center(w.width, w.height)
+		p.Hero.Sprite.X = p.Hero.Sprite.X + ((2*i)-(len(living)-1))*p.Hero.Sprite.Bounds().Dx()
+		start := w.walls().NearestOpen(p.Hero.Position(), anywhere)
+		p.Hero.Sprite.X = int(start.X)
+		p.Hero.Sprite.Y = int(start.Y)
+	}
+}
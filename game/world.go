@@ -0,0 +1,335 @@
+package game
+
+import (
+	"math/rand"
+
+	"github.com/markrzasa/arrowsaway/entity"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/level"
+	"github.com/markrzasa/arrowsaway/physics"
+	"github.com/markrzasa/arrowsaway/sprites"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+type State int
+
+const (
+	NextStage State = iota
+	Running
+	Paused
+	LostLife
+	GameOver
+	Winner
+)
+
+func (s State) String() string {
+	switch s {
+	case NextStage:
+		return "next stage"
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	case LostLife:
+		return "lost life"
+	case GameOver:
+		return "game over"
+	case Winner:
+		return "winner"
+	}
+	return "unknown"
+}
+
+// World holds every rule of the game and none of its presentation. It is
+// advanced one tick at a time from the players' intents and can run without
+// a window.
+type World struct {
+	height, width int
+
+	state State
+
+	// tick counts simulation steps and only advances while Running
+	tick int64
+
+	seed      int64
+	fixedSeed bool
+	rng       *rand.Rand
+
+	players      []*Player
+	nextPlayerId int
+
+	levelIndex int
+	levels     []*level.Level
+
+	enemies *sprites.EnemyStore
+
+	enemyGrid *physics.Grid
+	enemyList []*sprites.Enemy
+	enemyIds  []entity.ID
+	nearby    []int
+	hits      []arrowHit
+
+	// enemyArrowGrid holds the paths the enemy arrows took during their
+	// last update, in the order of enemyArrowIds
+	enemyArrowGrid *physics.Grid
+	enemyArrowIds  []entity.ID
+
+	// paths leads the enemies around the walls to the heroes and is
+	// searched once a tick for all of them
+	paths tilemap.Field
+
+	// buffers reused every tick so a running world does not allocate
+	intents   []input.Intent
+	living    []*Player
+	heroes    []*sprites.Sprite
+	positions []geom.Vector
+
+	arrows      *sprites.ArrowStore
+	enemyArrows *sprites.ArrowStore
+
+	pickups []*sprites.Pickup
+
+	kills int
+}
+
+// NewWorld creates a world ready for the first stage of a run. When
+// fixedSeed is false every run after the first is seeded from the one
+// before it, so a whole session is still reproducible from seed.
+func NewWorld(width, height int, seed int64, fixedSeed bool, levels []*level.Level) *World {
+	w := &World{
+		height:         height,
+		width:          width,
+		state:          NextStage,
+		seed:           seed,
+		fixedSeed:      fixedSeed,
+		levels:         levels,
+		enemies:        sprites.NewEnemyStore(),
+		arrows:         sprites.NewArrowStore(),
+		enemyArrows:    sprites.NewArrowStore(),
+		enemyGrid:      physics.NewGrid(physics.DefaultCellSize),
+		enemyArrowGrid: physics.NewGrid(physics.DefaultCellSize),
+	}
+	w.newRun()
+	return w
+}
+
+func (w *World) State() State {
+	return w.state
+}
+
+func (w *World) Tick() int64 {
+	return w.tick
+}
+
+func (w *World) Seed() int64 {
+	return w.seed
+}
+
+// FixedSeed reports whether every run uses Seed rather than a seed drawn from
+// the run before.
+func (w *World) FixedSeed() bool {
+	return w.fixedSeed
+}
+
+func (w *World) Width() int {
+	return w.width
+}
+
+func (w *World) Height() int {
+	return w.height
+}
+
+func (w *World) Resize(width, height int) {
+	w.width = width
+	w.height = height
+}
+
+func (w *World) Level() *level.Level {
+	return w.levels[w.levelIndex]
+}
+
+// Levels returns the levels of a run in the order they are played.
+func (w *World) Levels() []*level.Level {
+	return w.levels
+}
+
+// walls returns the walls of the current level's arena. Once every level
+// has been won there is no arena left, so until the next run starts heroes
+// stand in an open one.
+func (w *World) walls() *tilemap.Map {
+	if w.levelIndex >= len(w.levels) {
+		return nil
+	}
+	return w.levels[w.levelIndex].Map()
+}
+
+func (w *World) LevelIndex() int {
+	return w.levelIndex
+}
+
+func (w *World) Enemies() *sprites.EnemyStore {
+	return w.enemies
+}
+
+// Boss returns the boss of the current stage, or nil when there is none.
+func (w *World) Boss() *sprites.Enemy {
+	for _, e := range w.enemies.All() {
+		if e.Boss != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (w *World) Arrows() *sprites.ArrowStore {
+	return w.arrows
+}
+
+// EnemyArrows returns the projectiles shot by enemies.
+func (w *World) EnemyArrows() *sprites.ArrowStore {
+	return w.enemyArrows
+}
+
+func (w *World) Pickups() []*sprites.Pickup {
+	return w.pickups
+}
+
+// AtRunStart reports whether the world is waiting to start the first stage
+// of a run, which is when players may pick their weapon.
+func (w *World) AtRunStart() bool {
+	return w.levelIndex == 0 && w.levels[0].GetStage() == 0
+}
+
+// Pause stops a running stage, for example because every controller was
+// unplugged.
+func (w *World) Pause() {
+	if w.state == Running {
+		w.state = Paused
+	}
+}
+
+// newRun resets everything for a fresh attempt. The random source is seeded
+// again so a run started with the same seed and inputs plays out the same.
+func (w *World) newRun() {
+	if w.rng != nil && !w.fixedSeed {
+		w.seed = w.rng.Int63()
+	}
+	w.rng = rand.New(rand.NewSource(w.seed))
+	w.enemies.Clear()
+	w.arrows.Clear()
+	w.enemyArrows.Clear()
+	w.pickups = nil
+	w.kills = 0
+	w.tick = 0
+	for _, p := range w.players {
+		p.reset()
+	}
+	// the heroes are lined up on the first level's map, not the one the
+	// last run ended on
+	w.levelIndex = 0
+	for _, l := range w.levels {
+		l.Reset()
+	}
+	w.startHeroes()
+	w.levels[w.levelIndex].PopulateEnemies(w.width, w.height, w.heroPositions(), w.enemies, w.rng)
+}
+
+func (w *World) updateLevel() {
+	level := w.levels[w.levelIndex]
+	level.Update(w.width, w.height, w.heroPositions(), w.enemies, w.rng)
+	if level.Cleared(w.enemies) {
+		w.pickups = nil
+		w.enemyArrows.Clear()
+		// the heroes go back to the start before the enemies of the next
+		// stage are placed so none are placed on top of them
+		if level.Complete() {
+			w.levelIndex = w.levelIndex + 1
+			if w.levelIndex == len(w.levels) {
+				w.state = Winner
+			} else {
+				w.startHeroes()
+				w.levels[w.levelIndex].PopulateEnemies(w.width, w.height, w.heroPositions(), w.enemies, w.rng)
+				w.state = NextStage
+			}
+		} else {
+			level.NextStage()
+			w.startHeroes()
+			level.PopulateEnemies(w.width, w.height, w.heroPositions(), w.enemies, w.rng)
+			w.state = NextStage
+		}
+	}
+}
+
+// Update advances the world by one tick. intents holds what each player,
+// keyed by id, asked for this tick; players without an entry do nothing.
+func (w *World) Update(intents map[int]input.Intent) {
+	w.intents = w.intents[:0]
+	for _, p := range w.players {
+		p.intent = intents[p.Id]
+		w.intents = append(w.intents, p.intent)
+	}
+	menu := input.Menu(w.intents...)
+
+	switch w.state {
+	case NextStage:
+		if w.AtRunStart() {
+			for _, p := range w.players {
+				p.selectWeapon()
+			}
+		}
+		if menu.Confirm && len(w.players) > 0 {
+			w.startHeroes()
+			w.state = Running
+		}
+	case Running:
+		if menu.Pause {
+			w.state = Paused
+			break
+		}
+		if len(w.LivingPlayers()) == 0 {
+			w.state = GameOver
+			break
+		}
+		w.tick = w.tick + 1
+		for _, p := range w.LivingPlayers() {
+			p.Hero.Update(p.intent, w.height, w.width, w.walls())
+		}
+		w.indexEnemies()
+		w.indexEnemyArrows()
+		w.updateHeroes()
+		if w.state != Running {
+			// a hero was hit, and nothing else may happen this tick, such
+			// as the stage being cleared over the top of it
+			break
+		}
+		w.updatePickups()
+		w.updateArrows()
+		w.updateEnemies()
+		w.updateEnemyArrows()
+		w.updateLevel()
+	case Paused:
+		if menu.Pause {
+			w.state = Running
+		}
+	case LostLife:
+		if menu.Confirm {
+			w.startHeroes()
+			w.enemyArrows.Clear()
+			for _, id := range w.enemies.IDs() {
+				if !w.enemies.Get(id).IsAlive() {
+					w.enemies.Remove(id)
+				}
+			}
+			w.Level().Restart(w.width, w.height, w.heroPositions(), w.enemies, w.rng)
+			w.state = Running
+		}
+	case GameOver:
+		fallthrough
+	case Winner:
+		if menu.Confirm {
+			w.newRun()
+			w.state = NextStage
+		}
+	}
+}
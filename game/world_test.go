@@ -0,0 +1,370 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/level"
+	"github.com/markrzasa/arrowsaway/sprites"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+const (
+	testWidth  int = 1000
+	testHeight int = 1000
+)
+
+// newTestWorld returns a world with one player and a single open level
+// whose only enemy starts on the left edge, well away from the hero.
+func newTestWorld(t *testing.T) (*World, *Player) {
+	t.Helper()
+	enemyTypes := sprites.DefaultEnemyTypes()
+	stages := []level.Stage{
+		{
+			Hitpoints: level.Curve{From: 1, To: 1},
+			Waves: []level.Wave{
+				{Enemy: enemyTypes["goblin"], Enemies: 1, Pattern: level.Edge{Side: level.Left}},
+			},
+		},
+	}
+	levels := []*level.Level{level.NewLevel("test", images.Grass, nil, stages, nil)}
+	w := NewWorld(testWidth, testHeight, 1, true, levels)
+	return w, w.AddPlayer()
+}
+
+// play gives p each of the script's intents in turn, one tick apiece.
+func play(w *World, p *Player, script *input.Scripted) {
+	for !script.Done() {
+		w.Update(map[int]input.Intent{p.Id: script.Intent(p.Hero.Position())})
+	}
+}
+
+// repeat returns n copies of intent.
+func repeat(intent input.Intent, n int) []input.Intent {
+	intents := make([]input.Intent, n)
+	for i := range intents {
+		intents[i] = intent
+	}
+	return intents
+}
+
+func TestStateTransitions(t *testing.T) {
+	w, p := newTestWorld(t)
+	steps := []struct {
+		intent input.Intent
+		state  State
+		tick   int64
+	}{
+		{input.Intent{}, NextStage, 0},
+		{input.Intent{Confirm: true}, Running, 0},
+		{input.Intent{}, Running, 1},
+		{input.Intent{Pause: true}, Paused, 1},
+		{input.Intent{}, Paused, 1},
+		{input.Intent{Pause: true}, Running, 1},
+		{input.Intent{}, Running, 2},
+	}
+	for i, step := range steps {
+		play(w, p, input.NewScripted(step.intent))
+		if w.State() != step.state || w.Tick() != step.tick {
+			t.Fatalf("step %d: state %v at tick %d, want %v at tick %d", i, w.State(), w.Tick(), step.state, step.tick)
+		}
+	}
+}
+
+func TestHeroMoves(t *testing.T) {
+	w, p := newTestWorld(t)
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+	start := p.Hero.Position()
+
+	play(w, p, input.NewScripted(repeat(input.Intent{Move: geom.Vector{X: 1, Y: 0}}, 5)...))
+	if got, want := p.Hero.Position(), start.Add(geom.Vector{X: 50}); got != want {
+		t.Errorf("after moving right hero is at %v, want %v", got, want)
+	}
+	play(w, p, input.NewScripted(repeat(input.Intent{Move: geom.Vector{X: 0, Y: 0.5}}, 4)...))
+	if got, want := p.Hero.Position(), start.Add(geom.Vector{X: 50, Y: 20}); got != want {
+		t.Errorf("after moving down hero is at %v, want %v", got, want)
+	}
+}
+
+func TestHeroStaysInArena(t *testing.T) {
+	w, p := newTestWorld(t)
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+
+	play(w, p, input.NewScripted(repeat(input.Intent{Move: geom.Vector{X: 0.7, Y: 0.7}}, 200)...))
+	halfWidth, halfHeight := p.Hero.Sprite.FrameWidth()/2, p.Hero.Sprite.FrameHeight()/2
+	if p.Hero.Sprite.X != testWidth-halfWidth || p.Hero.Sprite.Y != testHeight-halfHeight {
+		t.Errorf("hero stopped at %d,%d, want %d,%d", p.Hero.Sprite.X, p.Hero.Sprite.Y, testWidth-halfWidth, testHeight-halfHeight)
+	}
+	if p.Hero.Velocity != (geom.Vector{}) {
+		t.Errorf("hero pinned in the corner still has velocity %v", p.Hero.Velocity)
+	}
+}
+
+func TestFireCooldown(t *testing.T) {
+	tests := []struct {
+		ticks  int
+		arrows int
+	}{
+		{1, 1},
+		{15, 1},
+		{16, 2},
+		{31, 3},
+	}
+	for _, tt := range tests {
+		w, p := newTestWorld(t)
+		play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+		fire := input.Intent{Aim: geom.Vector{X: 0, Y: -1}, Fire: true}
+		play(w, p, input.NewScripted(repeat(fire, tt.ticks)...))
+		if got := w.Arrows().Len(); got != tt.arrows {
+			t.Errorf("firing for %d ticks shot %d arrows, want %d", tt.ticks, got, tt.arrows)
+		}
+	}
+}
+
+func TestHitEndsTick(t *testing.T) {
+	w, p := newTestWorld(t)
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+
+	// kill the only enemy and wait for its body to be buried, which
+	// clears the last stage on the next tick
+	goblin := w.enemies.All()[0]
+	goblin.Shot(goblin.Type.Hitpoints, p.Hero.Sprite)
+	for !goblin.IsBuried() {
+		play(w, p, input.NewScripted(input.Intent{}))
+	}
+
+	// the shot of an enemy that is long gone hits the last life on that
+	// same tick
+	p.Lives = 1
+	w.enemyArrows.Spawn(sprites.NoOwner, sprites.PlainArrow, p.Hero.Position(), geom.Vector{X: 1}, 5, 100)
+	play(w, p, input.NewScripted(input.Intent{}))
+	if w.State() != GameOver {
+		t.Errorf("state %v, want %v", w.State(), GameOver)
+	}
+}
+
+func TestShotByEnemyArrow(t *testing.T) {
+	tests := []struct {
+		name  string
+		start geom.Vector
+		lives []int
+	}{
+		// the arrow flies through both heroes in one step but only the
+		// first it reaches is hit
+		{"through both heroes", geom.Vector{X: 300, Y: 500}, []int{2, 3}},
+		{"past both heroes", geom.Vector{X: 300, Y: 420}, []int{3, 3}},
+	}
+	for _, tt := range tests {
+		w, p := newTestWorld(t)
+		w.AddPlayer()
+		play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+		a := w.enemyArrows.Spawn(sprites.NoOwner, sprites.PlainArrow, tt.start, geom.Vector{X: 1}, 400, 1000)
+		a.Update()
+		play(w, p, input.NewScripted(input.Intent{}))
+		lives := []int{}
+		for _, player := range w.Players() {
+			lives = append(lives, player.Lives)
+		}
+		if !reflect.DeepEqual(lives, tt.lives) {
+			t.Errorf("%s: lives %v, want %v", tt.name, lives, tt.lives)
+		}
+	}
+}
+
+func TestLostLifeKeepsEnemiesClear(t *testing.T) {
+	enemyTypes := sprites.DefaultEnemyTypes()
+	stages := []level.Stage{
+		{
+			Hitpoints: level.Curve{From: 1, To: 1},
+			Waves: []level.Wave{
+				{Enemy: enemyTypes["goblin"], Enemies: 1, Pattern: level.Edge{Side: level.Left}},
+				// arrives across the middle once the hero has walked away
+				{Enemy: enemyTypes["goblin"], Enemies: 3, Pattern: level.Line{From: geom.Vector{X: 0.35, Y: 0.5}, To: geom.Vector{X: 0.65, Y: 0.5}}, Ticks: 30},
+			},
+		},
+	}
+	w := NewWorld(testWidth, testHeight, 1, true, []*level.Level{level.NewLevel("test", images.Grass, nil, stages, nil)})
+	p := w.AddPlayer()
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+	play(w, p, input.NewScripted(repeat(input.Intent{Move: geom.Vector{X: 0.7, Y: 0.7}}, 35)...))
+	if w.enemies.Len() != 4 {
+		t.Fatalf("%d enemies, want 4", w.enemies.Len())
+	}
+
+	w.enemyArrows.Spawn(sprites.NoOwner, sprites.PlainArrow, p.Hero.Position(), geom.Vector{X: 1}, 5, 100)
+	play(w, p, input.NewScripted(input.Intent{}))
+	if w.State() != LostLife {
+		t.Fatalf("state %v, want %v", w.State(), LostLife)
+	}
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+	for _, e := range w.enemies.All() {
+		if d := e.Position().Sub(p.Hero.Position()).Length(); d < level.SafeRadius {
+			t.Errorf("enemy restarted %.0f from the hero at %v", d, e.Position())
+		}
+	}
+	play(w, p, input.NewScripted(repeat(input.Intent{}, 5)...))
+	if w.State() != Running {
+		t.Errorf("state %v after continuing, want %v", w.State(), Running)
+	}
+}
+
+// rockMap returns a map of the test arena in 40 pixel tiles with a rock
+// width tiles across in the middle.
+func rockMap(t *testing.T, width int) *tilemap.Map {
+	t.Helper()
+	rows := []string{}
+	for i := 0; i < 25; i++ {
+		rows = append(rows, strings.Repeat(".", 25))
+	}
+	side := strings.Repeat(".", (25-width)/2)
+	rows[12] = side + strings.Repeat("o", width) + side
+	walls, err := tilemap.Parse(rows, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return walls
+}
+
+// oneGoblin returns a level called name with a single stage of one goblin
+// coming in from the left edge.
+func oneGoblin(name string, walls *tilemap.Map) *level.Level {
+	stages := []level.Stage{
+		{
+			Hitpoints: level.Curve{From: 1, To: 1},
+			Waves: []level.Wave{
+				{Enemy: sprites.DefaultEnemyTypes()["goblin"], Enemies: 1, Pattern: level.Edge{Side: level.Left}},
+			},
+		},
+	}
+	return level.NewLevel(name, images.Grass, walls, stages, nil)
+}
+
+func TestJoinMidStage(t *testing.T) {
+	// a rock in the middle of the arena where joining heroes were dropped
+	walls := rockMap(t, 1)
+	w := NewWorld(testWidth, testHeight, 1, true, []*level.Level{oneGoblin("test", walls)})
+	p := w.AddPlayer()
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+	play(w, p, input.NewScripted(repeat(input.Intent{Move: geom.Vector{X: 1}}, 20)...))
+
+	// park the enemy just off the middle of the arena
+	e := w.enemies.All()[0]
+	e.MoveStart(540, 500)
+	e.ToStart()
+
+	joined := w.AddPlayer()
+	if walls.IsSolid(joined.Hero.Position()) {
+		t.Errorf("hero joined inside the wall at %v", joined.Hero.Position())
+	}
+	if d := e.Position().Sub(joined.Hero.Position()).Length(); d < level.SafeRadius {
+		t.Errorf("hero joined %.0f from an enemy at %v", d, joined.Hero.Position())
+	}
+}
+
+func TestEnemiesSpreadOut(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y int
+	}{
+		{"on open floor", 500, 700},
+		// the hero is on the far side of a rock across the arena, so the
+		// enemies are pressed against it while they push each other aside
+		{"against the rock", 500, 530},
+	}
+	for _, tt := range tests {
+		walls := rockMap(t, 25)
+		w := NewWorld(testWidth, testHeight, 1, true, []*level.Level{oneGoblin("test", walls)})
+		p := w.AddPlayer()
+		play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+
+		// two enemies almost on top of each other
+		a := w.enemies.All()[0]
+		b := w.enemies.Spawn(a.Type, 0, 0, 1, w.rng)
+		a.MoveStart(tt.x, tt.y)
+		a.ToStart()
+		b.MoveStart(tt.x+1, tt.y)
+		b.ToStart()
+
+		spread := false
+		for tick := 0; tick < 60 && w.State() == Running; tick++ {
+			play(w, p, input.NewScripted(input.Intent{}))
+			for _, e := range []*sprites.Enemy{a, b} {
+				if walls.IsSolid(e.Position()) {
+					t.Fatalf("%s: enemy pushed into the rock at %v on tick %d", tt.name, e.Position(), tick)
+				}
+			}
+			if a.Position().Sub(b.Position()).Length() >= separationRadius/2 {
+				spread = true
+			}
+		}
+		if !spread {
+			t.Errorf("%s: enemies still %.1f apart, want at least %v", tt.name, a.Position().Sub(b.Position()).Length(), separationRadius/2)
+		}
+	}
+}
+
+func TestPlayAgain(t *testing.T) {
+	// the heroes start on the rock in the first arena and have to be moved
+	// off it when the run starts again
+	walls := rockMap(t, 3)
+	w := NewWorld(testWidth, testHeight, 1, true, []*level.Level{oneGoblin("first", walls), oneGoblin("second", nil)})
+	p := w.AddPlayer()
+	w.AddPlayer()
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+	for i := 0; i < 10 && w.State() != Winner; i++ {
+		for _, id := range w.enemies.IDs() {
+			w.enemies.Remove(id)
+		}
+		play(w, p, input.NewScripted(input.Intent{Confirm: w.State() == NextStage}))
+	}
+	if w.State() != Winner {
+		t.Fatalf("state %v after beating every level, want %v", w.State(), Winner)
+	}
+
+	// a player joining on the winner screen
+	w.AddPlayer()
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}))
+	if w.State() != NextStage || w.LevelIndex() != 0 {
+		t.Fatalf("state %v on level %d after playing again, want %v on level 0", w.State(), w.LevelIndex(), NextStage)
+	}
+	for _, player := range w.Players() {
+		if walls.IsSolid(player.Hero.Position()) {
+			t.Errorf("P%d starts inside the rock at %v", player.Id+1, player.Hero.Position())
+		}
+	}
+	play(w, p, input.NewScripted(input.Intent{Confirm: true}, input.Intent{}))
+	if w.State() != Running || w.Enemies().Len() != 1 {
+		t.Errorf("state %v with %d enemies, want %v with 1", w.State(), w.Enemies().Len(), Running)
+	}
+}
+
+// BenchmarkWorldUpdate runs stages of 1000 enemies against a hero that
+// keeps shooting in a circle, confirming whenever a stage starts or a life
+// is lost. The only allocations are the enemy and arrow pools and the
+// broadphase cells growing the first time they are needed, so a few
+// hundred updates average several allocations each while twenty thousand
+// average none.
+func BenchmarkWorldUpdate(b *testing.B) {
+	enemyTypes := sprites.DefaultEnemyTypes()
+	levels := level.DefaultLevels(enemyTypes, sprites.DefaultBossTypes(enemyTypes))
+	for _, l := range levels {
+		l.SetEnemiesPerStage(1000)
+	}
+	w := NewWorld(testWidth, testHeight, 1, true, levels)
+	p := w.AddPlayer()
+	intents := map[int]input.Intent{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intents[p.Id] = input.Intent{
+			Aim:     geom.FromAngle(float64(i) / 10),
+			Fire:    true,
+			Confirm: w.State() != Running,
+		}
+		w.Update(intents)
+	}
+}
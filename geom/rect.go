@@ -0,0 +1,81 @@
+package geom
+
+import (
+	"image"
+	"math"
+)
+
+type Rect struct {
+	Min, Max Vector
+}
+
+func RectFromImage(r image.Rectangle) Rect {
+	return Rect{
+		Min: Vector{X: float64(r.Min.X), Y: float64(r.Min.Y)},
+		Max: Vector{X: float64(r.Max.X), Y: float64(r.Max.Y)},
+	}
+}
+
+func (r Rect) Contains(p Vector) bool {
+	return r.Min.X <= p.X && p.X <= r.Max.X && r.Min.Y <= p.Y && p.Y <= r.Max.Y
+}
+
+// Closest returns the point in r nearest to p.
+func (r Rect) Closest(p Vector) Vector {
+	return Vector{
+		X: math.Max(r.Min.X, math.Min(p.X, r.Max.X)),
+		Y: math.Max(r.Min.Y, math.Min(p.Y, r.Max.Y)),
+	}
+}
+
+// SegmentRect reports whether the segment from..to touches r and, if it
+// does, the fraction of the way along the segment where it first does. A
+// segment that starts inside r hits at 0.
+func SegmentRect(from, to Vector, r Rect) (float64, bool) {
+	delta := to.Sub(from)
+	tMin := 0.0
+	tMax := 1.0
+	axes := [][4]float64{
+		{from.X, delta.X, r.Min.X, r.Max.X},
+		{from.Y, delta.Y, r.Min.Y, r.Max.Y},
+	}
+	for _, a := range axes {
+		start, d, min, max := a[0], a[1], a[2], a[3]
+		if d == 0 {
+			if start < min || start > max {
+				return 0, false
+			}
+			continue
+		}
+		t1 := (min - start) / d
+		t2 := (max - start) / d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin = math.Max(tMin, t1)
+		tMax = math.Min(tMax, t2)
+		if tMin > tMax {
+			return 0, false
+		}
+	}
+	return tMin, true
+}
+
+// RectAround returns the smallest rectangle containing both points.
+func RectAround(a, b Vector) Rect {
+	return Rect{
+		Min: Vector{X: math.Min(a.X, b.X), Y: math.Min(a.Y, b.Y)},
+		Max: Vector{X: math.Max(a.X, b.X), Y: math.Max(a.Y, b.Y)},
+	}
+}
+
+func (r Rect) Overlaps(o Rect) bool {
+	return r.Min.X <= o.Max.X && o.Min.X <= r.Max.X && r.Min.Y <= o.Max.Y && o.Min.Y <= r.Max.Y
+}
+
+func (r Rect) Union(o Rect) Rect {
+	return Rect{
+		Min: Vector{X: math.Min(r.Min.X, o.Min.X), Y: math.Min(r.Min.Y, o.Min.Y)},
+		Max: Vector{X: math.Max(r.Max.X, o.Max.X), Y: math.Max(r.Max.Y, o.Max.Y)},
+	}
+}
@@ -0,0 +1,35 @@
+package geom
+
+import "testing"
+
+func TestSegmentRect(t *testing.T) {
+	r := Rect{Max: Vector{X: 10, Y: 10}}
+	tests := []struct {
+		name     string
+		from, to Vector
+		want     float64
+		hit      bool
+	}{
+		{"straight through", Vector{X: -10, Y: 5}, Vector{X: 30, Y: 5}, 0.25, true},
+		{"straight through backwards", Vector{X: 30, Y: 5}, Vector{X: -10, Y: 5}, 0.5, true},
+		{"diagonally through", Vector{X: -5, Y: -5}, Vector{X: 15, Y: 15}, 0.25, true},
+		{"starting inside", Vector{X: 5, Y: 5}, Vector{X: 20, Y: 5}, 0, true},
+		{"ending on the edge", Vector{X: -10, Y: 5}, Vector{X: 0, Y: 5}, 1, true},
+		{"stopping short", Vector{X: -10, Y: 5}, Vector{X: -1, Y: 5}, 0, false},
+		{"alongside", Vector{X: -5, Y: -5}, Vector{X: -5, Y: 20}, 0, false},
+		{"across a corner's bounds", Vector{X: -5, Y: -2}, Vector{X: 2, Y: -5}, 0, false},
+		{"along an edge", Vector{X: -5}, Vector{X: 20}, 0.2, true},
+		{"through a corner", Vector{X: -5, Y: 5}, Vector{X: 5, Y: -5}, 0.5, true},
+		{"no length inside", Vector{X: 5, Y: 5}, Vector{X: 5, Y: 5}, 0, true},
+		{"no length outside", Vector{X: 15, Y: 5}, Vector{X: 15, Y: 5}, 0, false},
+	}
+	for _, tt := range tests {
+		got, hit := SegmentRect(tt.from, tt.to, r)
+		if hit != tt.hit || (hit && !approx(got, tt.want)) {
+			t.Errorf("%s: SegmentRect = %v, %v, want %v, %v", tt.name, got, hit, tt.want, tt.hit)
+		}
+		if hit && (got < 0 || got > 1) {
+			t.Errorf("%s: hit at %v, outside the segment", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package geom
+
+import (
+	"math"
+)
+
+type ShapeKind int
+
+const (
+	Circle ShapeKind = iota
+	OrientedRect
+	Capsule
+)
+
+// Hitbox describes a collision shape in a sprite's own unscaled and
+// unrotated space. Offset moves the shape away from the sprite's center.
+// Circles use Radius, oriented rectangles use HalfWidth and HalfHeight and
+// capsules are a segment reaching HalfWidth either side of the center along
+// the sprite's x axis, grown by Radius.
+type Hitbox struct {
+	Kind                          ShapeKind
+	Offset                        Vector
+	HalfWidth, HalfHeight, Radius float64
+}
+
+func CircleHitbox(radius float64) Hitbox {
+	return Hitbox{Kind: Circle, Radius: radius}
+}
+
+func RectHitbox(halfWidth, halfHeight float64) Hitbox {
+	return Hitbox{Kind: OrientedRect, HalfWidth: halfWidth, HalfHeight: halfHeight}
+}
+
+func CapsuleHitbox(halfLength, radius float64) Hitbox {
+	return Hitbox{Kind: Capsule, HalfWidth: halfLength, Radius: radius}
+}
+
+func (h Hitbox) WithOffset(x, y float64) Hitbox {
+	h.Offset = Vector{X: x, Y: y}
+	return h
+}
+
+// Place puts the hitbox in world space for a sprite centered on position,
+// rotated by radians and then scaled.
+func (h Hitbox) Place(position Vector, radians, scaleX, scaleY float64) Shape {
+	axis := FromAngle(radians)
+	normal := Vector{X: -axis.Y, Y: axis.X}
+	offset := axis.Scale(h.Offset.X * scaleX).Add(normal.Scale(h.Offset.Y * scaleY))
+	return Shape{
+		Kind:       h.Kind,
+		Center:     position.Add(offset),
+		Axis:       axis,
+		HalfWidth:  h.HalfWidth * scaleX,
+		HalfHeight: h.HalfHeight * scaleY,
+		Radius:     h.Radius * math.Max(scaleX, scaleY),
+	}
+}
+
+// Shape is a hitbox placed in world space. Axis is the unit vector along
+// the shape's own x axis.
+type Shape struct {
+	Kind                          ShapeKind
+	Center                        Vector
+	Axis                          Vector
+	HalfWidth, HalfHeight, Radius float64
+}
+
+func (s Shape) normal() Vector {
+	return Vector{X: -s.Axis.Y, Y: s.Axis.X}
+}
+
+func (s Shape) toLocal(p Vector) Vector {
+	d := p.Sub(s.Center)
+	return Vector{X: d.Dot(s.Axis), Y: d.Dot(s.normal())}
+}
+
+func (s Shape) toWorld(p Vector) Vector {
+	return s.Center.Add(s.Axis.Scale(p.X)).Add(s.normal().Scale(p.Y))
+}
+
+// core returns the segment a round shape is built around. For a circle both
+// ends are the center.
+func (s Shape) core() (Vector, Vector) {
+	if s.Kind == Capsule {
+		half := s.Axis.Scale(s.HalfWidth)
+		return s.Center.Sub(half), s.Center.Add(half)
+	}
+	return s.Center, s.Center
+}
+
+func (s Shape) localRect() Rect {
+	return Rect{
+		Min: Vector{X: -s.HalfWidth, Y: -s.HalfHeight},
+		Max: Vector{X: s.HalfWidth, Y: s.HalfHeight},
+	}
+}
+
+func (s Shape) corners() [4]Vector {
+	return [4]Vector{
+		s.toWorld(Vector{X: -s.HalfWidth, Y: -s.HalfHeight}),
+		s.toWorld(Vector{X: s.HalfWidth, Y: -s.HalfHeight}),
+		s.toWorld(Vector{X: s.HalfWidth, Y: s.HalfHeight}),
+		s.toWorld(Vector{X: -s.HalfWidth, Y: s.HalfHeight}),
+	}
+}
+
+// Bounds returns the axis aligned rectangle containing the shape.
+func (s Shape) Bounds() Rect {
+	if s.Kind == OrientedRect {
+		c := s.corners()
+		r := RectAround(c[0], c[1])
+		r = r.Union(RectAround(c[2], c[3]))
+		return r
+	}
+	a, b := s.core()
+	r := RectAround(a, b)
+	return Rect{
+		Min: Vector{X: r.Min.X - s.Radius, Y: r.Min.Y - s.Radius},
+		Max: Vector{X: r.Max.X + s.Radius, Y: r.Max.Y + s.Radius},
+	}
+}
+
+// Distance returns how far p is from the shape, zero if p is inside it.
+func (s Shape) Distance(p Vector) float64 {
+	if s.Kind == OrientedRect {
+		return s.boxDistance(p)
+	}
+	a, b := s.core()
+	return math.Max(0, pointSegmentDistance(p, a, b)-s.Radius)
+}
+
+func (s Shape) boxDistance(p Vector) float64 {
+	local := s.toLocal(p)
+	return s.localRect().Closest(local).Sub(local).Length()
+}
+
+func (s Shape) segmentBoxDistance(a, b Vector) float64 {
+	if _, ok := SegmentRect(s.toLocal(a), s.toLocal(b), s.localRect()); ok {
+		return 0
+	}
+	// the closest points of two separate convex shapes in the plane always
+	// include a vertex of one of them
+	d := math.Min(s.boxDistance(a), s.boxDistance(b))
+	for _, c := range s.corners() {
+		d = math.Min(d, pointSegmentDistance(c, a, b))
+	}
+	return d
+}
+
+// Overlap reports whether two shapes touch.
+func Overlap(a, b Shape) bool {
+	if a.Kind == OrientedRect && b.Kind == OrientedRect {
+		return boxesOverlap(a, b)
+	}
+	if b.Kind == OrientedRect {
+		a, b = b, a
+	}
+	if a.Kind == OrientedRect {
+		c, d := b.core()
+		return a.segmentBoxDistance(c, d) <= b.Radius
+	}
+	a1, a2 := a.core()
+	b1, b2 := b.core()
+	return segmentSegmentDistance(a1, a2, b1, b2) <= a.Radius+b.Radius
+}
+
+// boxesOverlap is a separating axis test between two oriented rectangles.
+func boxesOverlap(a, b Shape) bool {
+	ac := a.corners()
+	bc := b.corners()
+	for _, axis := range []Vector{a.Axis, a.normal(), b.Axis, b.normal()} {
+		aMin, aMax := project(ac, axis)
+		bMin, bMax := project(bc, axis)
+		if aMax < bMin || bMax < aMin {
+			return false
+		}
+	}
+	return true
+}
+
+func project(corners [4]Vector, axis Vector) (float64, float64) {
+	min := corners[0].Dot(axis)
+	max := min
+	for _, c := range corners[1:] {
+		d := c.Dot(axis)
+		min = math.Min(min, d)
+		max = math.Max(max, d)
+	}
+	return min, max
+}
+
+// SegmentShape reports whether the segment from..to touches s and, if it
+// does, the fraction of the way along the segment where it first does.
+func SegmentShape(from, to Vector, s Shape) (float64, bool) {
+	switch s.Kind {
+	case OrientedRect:
+		return SegmentRect(s.toLocal(from), s.toLocal(to), s.localRect())
+	case Capsule:
+		a, b := s.core()
+		core := Shape{Kind: OrientedRect, Center: s.Center, Axis: s.Axis, HalfWidth: s.HalfWidth, HalfHeight: s.Radius}
+		best, hit := SegmentShape(from, to, core)
+		for _, end := range []Vector{a, b} {
+			if t, ok := SegmentCircle(from, to, end, s.Radius); ok && (!hit || t < best) {
+				best, hit = t, true
+			}
+		}
+		return best, hit
+	default:
+		return SegmentCircle(from, to, s.Center, s.Radius)
+	}
+}
+
+// SegmentCircle reports whether the segment from..to touches the circle and
+// the fraction of the way along the segment where it first does.
+func SegmentCircle(from, to, center Vector, radius float64) (float64, bool) {
+	d := to.Sub(from)
+	f := from.Sub(center)
+	c := f.Dot(f) - (radius * radius)
+	if c <= 0 {
+		return 0, true
+	}
+	a := d.Dot(d)
+	if a == 0 {
+		return 0, false
+	}
+	b := 2 * f.Dot(d)
+	discriminant := (b * b) - (4 * a * c)
+	if discriminant < 0 {
+		return 0, false
+	}
+	t := (-b - math.Sqrt(discriminant)) / (2 * a)
+	if t < 0 || t > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+func pointSegmentDistance(p, a, b Vector) float64 {
+	ab := b.Sub(a)
+	lengthSquared := ab.Dot(ab)
+	if lengthSquared == 0 {
+		return p.Sub(a).Length()
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/lengthSquared))
+	return p.Sub(a.Add(ab.Scale(t))).Length()
+}
+
+func segmentsCross(a, b, c, d Vector) bool {
+	cross := func(o, p, q Vector) float64 {
+		return ((p.X - o.X) * (q.Y - o.Y)) - ((p.Y - o.Y) * (q.X - o.X))
+	}
+	d1 := cross(c, d, a)
+	d2 := cross(c, d, b)
+	d3 := cross(a, b, c)
+	d4 := cross(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func segmentSegmentDistance(a, b, c, d Vector) float64 {
+	if segmentsCross(a, b, c, d) {
+		return 0
+	}
+	return math.Min(
+		math.Min(pointSegmentDistance(a, c, d), pointSegmentDistance(b, c, d)),
+		math.Min(pointSegmentDistance(c, a, b), pointSegmentDistance(d, a, b)),
+	)
+}
@@ -0,0 +1,149 @@
+package geom
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func near(a, b Vector) bool {
+	return approx(a.X, b.X) && approx(a.Y, b.Y)
+}
+
+// at places h unscaled at x, y turned by radians.
+func at(h Hitbox, x, y, radians float64) Shape {
+	return h.Place(Vector{X: x, Y: y}, radians, 1, 1)
+}
+
+func TestPlace(t *testing.T) {
+	tests := []struct {
+		name           string
+		hitbox         Hitbox
+		radians        float64
+		scaleX, scaleY float64
+		want           Shape
+	}{
+		{"circle", CircleHitbox(5), 0, 1, 1, Shape{Kind: Circle, Center: Vector{X: 100, Y: 100}, Axis: Vector{X: 1}, Radius: 5}},
+		{"circle grows with the larger scale", CircleHitbox(5), 0, 2, 3, Shape{Kind: Circle, Center: Vector{X: 100, Y: 100}, Axis: Vector{X: 1}, Radius: 15}},
+		{"scaled box", RectHitbox(4, 2), 0, 2, 3, Shape{Kind: OrientedRect, Center: Vector{X: 100, Y: 100}, Axis: Vector{X: 1}, HalfWidth: 8, HalfHeight: 6}},
+		{"offset along x", RectHitbox(4, 2).WithOffset(10, 0), 0, 2, 1, Shape{Kind: OrientedRect, Center: Vector{X: 120, Y: 100}, Axis: Vector{X: 1}, HalfWidth: 8, HalfHeight: 2}},
+		{"offset along y", CircleHitbox(1).WithOffset(0, 4), 0, 1, 2, Shape{Kind: Circle, Center: Vector{X: 100, Y: 108}, Axis: Vector{X: 1}, Radius: 2}},
+		{"offset turns with the sprite", RectHitbox(4, 2).WithOffset(10, 0), math.Pi / 2, 2, 3, Shape{Kind: OrientedRect, Center: Vector{X: 100, Y: 120}, Axis: Vector{Y: 1}, HalfWidth: 8, HalfHeight: 6}},
+		{"offset both ways turned", CircleHitbox(1).WithOffset(10, 5), math.Pi / 2, 1, 1, Shape{Kind: Circle, Center: Vector{X: 95, Y: 110}, Axis: Vector{Y: 1}, Radius: 1}},
+		{"capsule", CapsuleHitbox(10, 3), 0, 2, 1, Shape{Kind: Capsule, Center: Vector{X: 100, Y: 100}, Axis: Vector{X: 1}, HalfWidth: 20, Radius: 6}},
+	}
+	for _, tt := range tests {
+		got := tt.hitbox.Place(Vector{X: 100, Y: 100}, tt.radians, tt.scaleX, tt.scaleY)
+		if got.Kind != tt.want.Kind || !near(got.Center, tt.want.Center) || !near(got.Axis, tt.want.Axis) ||
+			!approx(got.HalfWidth, tt.want.HalfWidth) || !approx(got.HalfHeight, tt.want.HalfHeight) || !approx(got.Radius, tt.want.Radius) {
+			t.Errorf("%s: placed %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	circle := CircleHitbox(10)
+	box := RectHitbox(5, 5)
+	bar := RectHitbox(10, 1)
+	capsule := CapsuleHitbox(10, 3)
+	tests := []struct {
+		name string
+		a, b Shape
+		want bool
+	}{
+		{"circles touching", at(circle, 0, 0, 0), at(circle, 20, 0, 0), true},
+		{"circles apart", at(circle, 0, 0, 0), at(circle, 20.01, 0, 0), false},
+		{"circle touching a box", at(circle, 0, 0, 0), at(box, 15, 0, 0), true},
+		{"circle apart from a box", at(circle, 0, 0, 0), at(box, 15.01, 0, 0), false},
+		{"circle near a box corner", at(circle, 0, 0, 0), at(box, 14, 14, 0), false},
+		{"box near a circle", at(box, 14, 14, 0), at(circle, 0, 0, 0), false},
+		{"circle inside a box", at(CircleHitbox(1), 2, 2, 0), at(box, 0, 0, 0), true},
+		{"circle on a bar", at(CircleHitbox(1), 9, 0, 0), at(bar, 0, 0, 0), true},
+		{"circle beside a turned bar", at(CircleHitbox(1), 9, 0, 0), at(bar, 0, 0, math.Pi/4), false},
+		{"circle on a turned bar", at(CircleHitbox(1), 7, 7, 0), at(bar, 0, 0, math.Pi/4), true},
+		{"boxes touching", at(box, 0, 0, 0), at(box, 10, 0, 0), true},
+		{"boxes apart", at(box, 0, 0, 0), at(box, 10.01, 0, 0), false},
+		{"box and a diamond overlapping", at(box, 0, 0, 0), at(box, 8, 8, math.Pi/4), true},
+		{"box and a diamond apart", at(box, 0, 0, 0), at(box, 11, 11, math.Pi/4), false},
+		{"crossed bars", at(bar, 0, 0, 0), at(bar, 0, 0, math.Pi/2), true},
+		{"circle touching a capsule's side", at(CircleHitbox(2), 0, 5, 0), at(capsule, 0, 0, 0), true},
+		{"circle clear of a capsule's side", at(CircleHitbox(2), 0, 5.01, 0), at(capsule, 0, 0, 0), false},
+		{"circle on a capsule's end", at(CircleHitbox(2), 14, 0, 0), at(capsule, 0, 0, 0), true},
+		{"circle past a capsule's rounded end", at(CircleHitbox(2), 14, 4, 0), at(capsule, 0, 0, 0), false},
+		{"circle on the end of a turned capsule", at(CircleHitbox(1), 0, 12, 0), at(capsule, 0, 0, math.Pi/2), true},
+		{"circle off the end of a capsule", at(CircleHitbox(1), 0, 12, 0), at(capsule, 0, 0, 0), false},
+		{"capsule touching a box", at(capsule, 0, 0, 0), at(RectHitbox(2, 2), 0, 5, 0), true},
+		{"capsule clear of a box", at(capsule, 0, 0, 0), at(RectHitbox(2, 2), 0, 5.01, 0), false},
+		{"box clear of a capsule's end", at(RectHitbox(2, 2), 15, 5, 0), at(capsule, 0, 0, 0), false},
+		{"crossed capsules", at(capsule, 0, 0, 0), at(capsule, 0, 0, math.Pi/2), true},
+		{"side by side capsules touching", at(capsule, 0, 0, 0), at(capsule, 0, 6, 0), true},
+		{"side by side capsules apart", at(capsule, 0, 0, 0), at(capsule, 0, 6.01, 0), false},
+	}
+	for _, tt := range tests {
+		if got := Overlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Overlap = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := Overlap(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s swapped: Overlap = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentShape(t *testing.T) {
+	capsule := at(CapsuleHitbox(10, 3), 0, 0, 0)
+	tests := []struct {
+		name     string
+		from, to Vector
+		s        Shape
+		want     float64
+		hit      bool
+	}{
+		{"circle", Vector{X: -20}, Vector{X: 20}, at(CircleHitbox(5), 0, 0, 0), 0.375, true},
+		{"box", Vector{X: -20}, Vector{X: 20}, at(RectHitbox(5, 5), 0, 0, 0), 0.375, true},
+		{"turned bar", Vector{X: -5, Y: 5}, Vector{X: 5, Y: 5}, at(RectHitbox(10, 1), 0, 0, math.Pi/2), 0.4, true},
+		{"past a turned bar", Vector{X: -5, Y: 12}, Vector{X: 5, Y: 12}, at(RectHitbox(10, 1), 0, 0, math.Pi/2), 0, false},
+		{"capsule side", Vector{Y: -10}, Vector{Y: 10}, capsule, 0.35, true},
+		{"capsule end on", Vector{X: -20}, Vector{X: 20}, capsule, 0.175, true},
+		{"capsule rounded end", Vector{X: 12, Y: -10}, Vector{X: 12, Y: 10}, capsule, (10 - math.Sqrt(5)) / 20, true},
+		{"grazing a capsule's rounded end", Vector{X: 13, Y: -10}, Vector{X: 13, Y: 10}, capsule, 0.5, true},
+		{"past a capsule's rounded end", Vector{X: 13.01, Y: -10}, Vector{X: 13.01, Y: 10}, capsule, 0, false},
+	}
+	for _, tt := range tests {
+		got, hit := SegmentShape(tt.from, tt.to, tt.s)
+		if hit != tt.hit || (hit && !approx(got, tt.want)) {
+			t.Errorf("%s: SegmentShape = %v, %v, want %v, %v", tt.name, got, hit, tt.want, tt.hit)
+		}
+	}
+}
+
+func TestSegmentCircle(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to Vector
+		want     float64
+		hit      bool
+	}{
+		{"straight through", Vector{X: -20}, Vector{X: 20}, 0.375, true},
+		{"straight through backwards", Vector{X: 20}, Vector{X: -20}, 0.375, true},
+		{"starting inside", Vector{X: 1, Y: 1}, Vector{X: 20}, 0, true},
+		{"ending on the edge", Vector{X: -20}, Vector{X: -5}, 1, true},
+		{"stopping short", Vector{X: -20}, Vector{X: -10}, 0, false},
+		{"heading away", Vector{X: 10}, Vector{X: 20}, 0, false},
+		{"missing", Vector{X: -20, Y: 10}, Vector{X: 20, Y: 10}, 0, false},
+		{"tangent", Vector{X: -20, Y: 5}, Vector{X: 20, Y: 5}, 0.5, true},
+		{"no length inside", Vector{X: 1}, Vector{X: 1}, 0, true},
+		{"no length outside", Vector{X: 10}, Vector{X: 10}, 0, false},
+	}
+	for _, tt := range tests {
+		got, hit := SegmentCircle(tt.from, tt.to, Vector{}, 5)
+		if hit != tt.hit || (hit && !approx(got, tt.want)) {
+			t.Errorf("%s: SegmentCircle = %v, %v, want %v, %v", tt.name, got, hit, tt.want, tt.hit)
+		}
+		if hit && (got < 0 || got > 1) {
+			t.Errorf("%s: hit at %v, outside the segment", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package geom
+
+import (
+	"math"
+)
+
+type Vector struct {
+	X, Y float64
+}
+
+func FromAngle(radians float64) Vector {
+	return Vector{X: math.Cos(radians), Y: math.Sin(radians)}
+}
+
+func (v Vector) Add(o Vector) Vector {
+	return Vector{X: v.X + o.X, Y: v.Y + o.Y}
+}
+
+func (v Vector) Sub(o Vector) Vector {
+	return Vector{X: v.X - o.X, Y: v.Y - o.Y}
+}
+
+func (v Vector) Scale(s float64) Vector {
+	return Vector{X: v.X * s, Y: v.Y * s}
+}
+
+func (v Vector) Dot(o Vector) float64 {
+	return (v.X * o.X) + (v.Y * o.Y)
+}
+
+func (v Vector) Length() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+func (v Vector) Angle() float64 {
+	return math.Atan2(v.Y, v.X)
+}
+
+func (v Vector) IsZero() bool {
+	return v.X == 0 && v.Y == 0
+}
+
+func (v Vector) Normalize() Vector {
+	length := v.Length()
+	if length == 0 {
+		return Vector{}
+	}
+	return Vector{X: v.X / length, Y: v.Y / length}
+}
+
+// Rotate turns v by radians in the direction Angle increases.
+func (v Vector) Rotate(radians float64) Vector {
+	sin, cos := math.Sincos(radians)
+	return Vector{X: (v.X * cos) - (v.Y * sin), Y: (v.X * sin) + (v.Y * cos)}
+}
+
+// Perp returns v turned a quarter turn.
+func (v Vector) Perp() Vector {
+	return Vector{X: -v.Y, Y: v.X}
+}
+
+// InterceptTime returns how long a projectile fired from origin at speed
+// takes to meet a target at target moving by velocity every step. When the
+// target outruns the projectile the time to reach where it is now is
+// returned instead.
+func InterceptTime(origin, target, velocity Vector, speed float64) float64 {
+	d := target.Sub(origin)
+	a := velocity.Dot(velocity) - (speed * speed)
+	b := 2 * d.Dot(velocity)
+	c := d.Dot(d)
+	straight := math.Sqrt(c) / speed
+	if math.Abs(a) < 1e-9 {
+		if b >= 0 {
+			return straight
+		}
+		return -c / b
+	}
+	disc := (b * b) - (4 * a * c)
+	if disc < 0 {
+		return straight
+	}
+	root := math.Sqrt(disc)
+	t1 := (-b - root) / (2 * a)
+	t2 := (-b + root) / (2 * a)
+	if t1 > t2 {
+		t1, t2 = t2, t1
+	}
+	if t1 > 0 {
+		return t1
+	}
+	if t2 > 0 {
+		return t2
+	}
+	return straight
+}
@@ -0,0 +1,35 @@
+package geom
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInterceptTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		velocity Vector
+		want     float64
+		meets    bool
+	}{
+		{"standing still", Vector{}, 10, true},
+		{"crossing", Vector{Y: 5}, math.Sqrt(10000.0 / 75), true},
+		{"closing in", Vector{X: -10}, 5, true},
+		{"closing in slowly", Vector{X: -5}, 100.0 / 15, true},
+		{"running away slower", Vector{X: 5}, 20, true},
+		// no intercept, so the time to where the target is now
+		{"running away as fast", Vector{X: 10}, 10, false},
+		{"running away faster", Vector{X: 20}, 10, false},
+		{"crossing too fast", Vector{Y: 20}, 10, false},
+	}
+	target := Vector{X: 100}
+	for _, tt := range tests {
+		got := InterceptTime(Vector{}, target, tt.velocity, 10)
+		if !approx(got, tt.want) {
+			t.Errorf("%s: InterceptTime = %v, want %v", tt.name, got, tt.want)
+		}
+		if meets := approx(target.Add(tt.velocity.Scale(got)).Length(), 10*got); meets != tt.meets {
+			t.Errorf("%s: projectile meets the target %v, want %v", tt.name, meets, tt.meets)
+		}
+	}
+}
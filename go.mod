@@ -3,7 +3,6 @@ module github.com/markrzasa/arrowsaway
 go 1.17
 
 require (
-	github.com/google/uuid v1.3.0
 	github.com/hajimehoshi/ebiten/v2 v2.2.5
 	golang.org/x/image v0.0.0-20211028202545-6944b10bf410
 )
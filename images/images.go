@@ -3,15 +3,25 @@ package images
 import (
 	"bytes"
 	_ "embed"
+	"fmt"
+	"image"
 	"image/png"
 	"log"
-
-	"github.com/hajimehoshi/ebiten/v2"
+	"sync"
 )
 
 //go:embed arrow.png
 var arrow []byte
 
+//go:embed explosiveArrow.png
+var explosiveArrow []byte
+
+//go:embed piercingArrow.png
+var piercingArrow []byte
+
+//go:embed spreadArrow.png
+var spreadArrow []byte
+
 //go:embed enemyHealth.png
 var enemyHealth []byte
 
@@ -30,43 +40,111 @@ var hero []byte
 //go:embed life.png
 var life []byte
 
+//go:embed rock.png
+var rock []byte
+
 //go:embed skeleton.png
 var skeleton []byte
 
-type Images struct {
-	Arrow       *ebiten.Image
-	EnemyHealth *ebiten.Image
-	Goblin      *ebiten.Image
-	Grass       *ebiten.Image
-	Hero        *ebiten.Image
-	Life        *ebiten.Image
-	Skeleton    *ebiten.Image
-	Stone       *ebiten.Image
+//go:embed wall.png
+var wall []byte
+
+const (
+	Arrow          = "arrow"
+	EnemyHealth    = "enemyHealth"
+	ExplosiveArrow = "explosiveArrow"
+	Goblin         = "goblin"
+	Grass          = "grass"
+	Hero           = "hero"
+	Life           = "life"
+	PiercingArrow  = "piercingArrow"
+	Rock           = "rock"
+	Skeleton       = "skeleton"
+	SpreadArrow    = "spreadArrow"
+	Stone          = "stone"
+	Wall           = "wall"
+)
+
+var files = map[string][]byte{
+	Arrow:          arrow,
+	EnemyHealth:    enemyHealth,
+	ExplosiveArrow: explosiveArrow,
+	Goblin:         goblin,
+	Grass:          grass,
+	Hero:           hero,
+	Life:           life,
+	PiercingArrow:  piercingArrow,
+	Rock:           rock,
+	Skeleton:       skeleton,
+	SpreadArrow:    spreadArrow,
+	Stone:          stone,
+	Wall:           wall,
 }
 
-var images *Images = nil
+var (
+	sizes = map[string]image.Point{}
+	lock  sync.Mutex
+)
 
-func newImage(imageBytes []byte) *ebiten.Image {
-	image, err := png.Decode(bytes.NewReader(imageBytes))
+func imageBytes(name string) []byte {
+	lock.Lock()
+	defer lock.Unlock()
+	b, ok := files[name]
+	if !ok {
+		log.Fatalf("unknown image %q", name)
+	}
+	return b
+}
+
+// Has reports whether there is an image called name.
+func Has(name string) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	_, ok := files[name]
+	return ok
+}
+
+// Add makes the PNG in data available as name, for images that are loaded
+// at run time rather than built into the game.
+func Add(name string, data []byte) error {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("image %s: %w", name, err)
 	}
-	return ebiten.NewImageFromImage(image)
+	lock.Lock()
+	defer lock.Unlock()
+	files[name] = data
+	sizes[name] = image.Point{X: config.Width, Y: config.Height}
+	return nil
 }
 
-func GetImages() *Images {
-	if images == nil {
-		images = &Images{
-			Arrow:       newImage(arrow),
-			EnemyHealth: newImage(enemyHealth),
-			Goblin:      newImage(goblin),
-			Grass:       newImage(grass),
-			Hero:        newImage(hero),
-			Life:        newImage(life),
-			Skeleton:    newImage(skeleton),
-			Stone:       newImage(stone),
-		}	
+// Decode returns the named image. Rendering code turns it into a texture;
+// everything else only needs its Size.
+func Decode(name string) image.Image {
+	image, err := png.Decode(bytes.NewReader(imageBytes(name)))
+	if err != nil {
+		log.Fatal(err)
 	}
+	return image
+}
 
-	return images
+// Size returns the width and height of the named image without decoding its
+// pixels.
+func Size(name string) (int, int) {
+	lock.Lock()
+	defer lock.Unlock()
+	size, ok := sizes[name]
+	if !ok {
+		b, ok := files[name]
+		if !ok {
+			log.Fatalf("unknown image %q", name)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(b))
+		if err != nil {
+			log.Fatal(err)
+		}
+		size = image.Point{X: config.Width, Y: config.Height}
+		sizes[name] = size
+	}
+	return size.X, size.Y
 }
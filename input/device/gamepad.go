@@ -0,0 +1,53 @@
+package device
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+)
+
+const (
+	fireThreshold float64 = 0.5
+)
+
+type Gamepad struct {
+	ID ebiten.GamepadID
+}
+
+func NewGamepad(id ebiten.GamepadID) *Gamepad {
+	return &Gamepad{
+		ID: id,
+	}
+}
+
+func (g *Gamepad) stick(horizontal, vertical ebiten.StandardGamepadAxis) geom.Vector {
+	x := ebiten.StandardGamepadAxisValue(g.ID, horizontal)
+	y := ebiten.StandardGamepadAxisValue(g.ID, vertical)
+	return geom.Vector{
+		X: math.Round(x*10) / 10,
+		Y: math.Round(y*10) / 10,
+	}
+}
+
+func (g *Gamepad) isButtonPressed() bool {
+	for b := ebiten.StandardGamepadButtonRightBottom; b < ebiten.StandardGamepadButtonMax; b++ {
+		if ebiten.IsStandardGamepadButtonPressed(g.ID, b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gamepad) Intent(origin geom.Vector) input.Intent {
+	aim := g.stick(ebiten.StandardGamepadAxisRightStickHorizontal, ebiten.StandardGamepadAxisRightStickVertical)
+	return input.Intent{
+		Move:    g.stick(ebiten.StandardGamepadAxisLeftStickHorizontal, ebiten.StandardGamepadAxisLeftStickVertical),
+		Aim:     aim,
+		Fire:    math.Abs(aim.X) > fireThreshold || math.Abs(aim.Y) > fireThreshold,
+		Confirm: g.isButtonPressed(),
+		Pause:   inpututil.IsStandardGamepadButtonJustPressed(g.ID, ebiten.StandardGamepadButtonCenterRight),
+	}
+}
@@ -0,0 +1,87 @@
+package device
+
+import (
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+)
+
+type KeyboardMouse struct {
+	cursorX, cursorY int
+	mouseActive      bool
+}
+
+func NewKeyboardMouse() *KeyboardMouse {
+	x, y := ebiten.CursorPosition()
+	return &KeyboardMouse{
+		cursorX:     x,
+		cursorY:     y,
+		mouseActive: false,
+	}
+}
+
+func KeyboardAvailable() bool {
+	return runtime.GOOS != "android" && runtime.GOOS != "ios"
+}
+
+func isAnyKeyPressed(keys ...ebiten.Key) bool {
+	for _, k := range keys {
+		if ebiten.IsKeyPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAnyKeyJustPressed(keys ...ebiten.Key) bool {
+	for _, k := range keys {
+		if inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *KeyboardMouse) move() geom.Vector {
+	move := geom.Vector{}
+	if isAnyKeyPressed(ebiten.KeyA, ebiten.KeyArrowLeft) {
+		move.X = move.X - 1
+	}
+	if isAnyKeyPressed(ebiten.KeyD, ebiten.KeyArrowRight) {
+		move.X = move.X + 1
+	}
+	if isAnyKeyPressed(ebiten.KeyW, ebiten.KeyArrowUp) {
+		move.Y = move.Y - 1
+	}
+	if isAnyKeyPressed(ebiten.KeyS, ebiten.KeyArrowDown) {
+		move.Y = move.Y + 1
+	}
+	return move.Normalize()
+}
+
+func (k *KeyboardMouse) Intent(origin geom.Vector) input.Intent {
+	// the mouse only takes over aiming once it has been used so it does not
+	// fight with a gamepad's right stick
+	x, y := ebiten.CursorPosition()
+	fire := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	if fire || x != k.cursorX || y != k.cursorY {
+		k.mouseActive = true
+	}
+	k.cursorX, k.cursorY = x, y
+
+	aim := geom.Vector{}
+	if k.mouseActive {
+		aim = geom.Vector{X: float64(x) - origin.X, Y: float64(y) - origin.Y}.Normalize()
+	}
+
+	return input.Intent{
+		Move:    k.move(),
+		Aim:     aim,
+		Fire:    fire && !aim.IsZero(),
+		Confirm: isAnyKeyJustPressed(ebiten.KeyEnter, ebiten.KeySpace),
+		Pause:   isAnyKeyJustPressed(ebiten.KeyEscape, ebiten.KeyP),
+	}
+}
@@ -0,0 +1,39 @@
+package input
+
+import (
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// Intent is everything a player asked for during a single frame. Move and Aim
+// are in screen space with lengths of at most one.
+type Intent struct {
+	Move    geom.Vector
+	Aim     geom.Vector
+	Fire    bool
+	Confirm bool
+	Pause   bool
+}
+
+// IsIdle reports whether the player did anything that should count as
+// joining the game. Aiming alone does not count.
+func (i Intent) IsIdle() bool {
+	return i.Move.IsZero() && !i.Fire && !i.Confirm && !i.Pause
+}
+
+// Source produces an Intent once per frame. origin is the position of the
+// hero being controlled so pointer based sources can aim relative to it.
+type Source interface {
+	Intent(origin geom.Vector) Intent
+}
+
+// Menu combines the menu buttons of every player's intent, so that any
+// player can confirm or pause for everyone. Only Confirm and Pause are set
+// in the result.
+func Menu(intents ...Intent) Intent {
+	menu := Intent{}
+	for _, i := range intents {
+		menu.Confirm = menu.Confirm || i.Confirm
+		menu.Pause = menu.Pause || i.Pause
+	}
+	return menu
+}
@@ -0,0 +1,26 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+func TestMenu(t *testing.T) {
+	tests := []struct {
+		name    string
+		intents []Intent
+		want    Intent
+	}{
+		{"nobody", nil, Intent{}},
+		{"one confirms", []Intent{{}, {Confirm: true}}, Intent{Confirm: true}},
+		{"one pauses", []Intent{{Pause: true}, {}}, Intent{Pause: true}},
+		{"both", []Intent{{Confirm: true}, {Pause: true}}, Intent{Confirm: true, Pause: true}},
+		{"playing", []Intent{{Move: geom.Vector{X: 1}, Aim: geom.Vector{Y: 1}, Fire: true}}, Intent{}},
+	}
+	for _, tt := range tests {
+		if got := Menu(tt.intents...); got != tt.want {
+			t.Errorf("%s: Menu = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package input
+
+import (
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// Scripted is a Source that plays back a fixed list of intents, one per
+// frame, and then reports no input at all.
+type Scripted struct {
+	intents []Intent
+	frame   int
+}
+
+func NewScripted(intents ...Intent) *Scripted {
+	return &Scripted{
+		intents: intents,
+		frame:   0,
+	}
+}
+
+func (s *Scripted) Intent(origin geom.Vector) Intent {
+	if s.Done() {
+		return Intent{}
+	}
+	i := s.intents[s.frame]
+	s.frame = s.frame + 1
+	return i
+}
+
+func (s *Scripted) Done() bool {
+	return s.frame >= len(s.intents)
+}
@@ -1,41 +1,86 @@
 package level
 
 import (
-	"github.com/google/uuid"
-	"github.com/hajimehoshi/ebiten/v2"
+	"math"
+	"math/rand"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
 	"github.com/markrzasa/arrowsaway/sprites"
+	"github.com/markrzasa/arrowsaway/tilemap"
 )
 
-const (
-	numStages int = 2
-)
+// Stage is one stage of a level, its Waves arriving in order. The
+// hitpoints of the enemies in each wave follow Hitpoints.
+type Stage struct {
+	Waves     []Wave
+	Hitpoints Curve
+}
 
+// Curve sets the hitpoints of the enemies of a wave as a multiple of their
+// type's hitpoints, rising evenly from From for the first enemy to To for
+// the last.
+type Curve struct {
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+}
+
+func (c Curve) hitpoints(base, i, n int) int {
+	multiple := c.From
+	if n > 1 {
+		multiple = multiple + ((c.To - c.From) * float64(i) / float64(n-1))
+	}
+	return int(math.Max(1, math.Round(float64(base)*multiple)))
+}
+
+// Level is a run of stages fought on one background, finishing with a
+// fight against its boss if it has one.
 type Level struct {
-	name       string
-	enemyImage *ebiten.Image
-	numEnemies int
-	stage      int
-	bgImage    *ebiten.Image
+	name    string
+	stages  []Stage
+	boss    *sprites.BossType
+	stage   int
+	bgImage string
+	walls   *tilemap.Map
+
+	wave       int
+	stageTicks int64
+	spawned    int
+	points     []geom.Vector
 }
 
-func (l *Level) GetBackground() *ebiten.Image {
+func (l *Level) GetBackground() string {
 	return l.bgImage
 }
 
-func (l *Level) GetName() string {
-	return l.name
+// Map returns the walls and rocks of the level's arena, or nil for an open
+// arena.
+func (l *Level) Map() *tilemap.Map {
+	return l.walls
 }
 
-func (l *Level) GetNumEnemies() int {
-	return l.numEnemies
+func (l *Level) GetName() string {
+	return l.name
 }
 
 func (l *Level) GetStage() int {
 	return l.stage
 }
 
+// NumStages returns how many stages the level has, counting the boss.
+func (l *Level) NumStages() int {
+	if l.boss != nil {
+		return len(l.stages) + 1
+	}
+	return len(l.stages)
+}
+
+func (l *Level) IsBossStage() bool {
+	return l.boss != nil && l.stage == len(l.stages)
+}
+
 func (l *Level) Complete() bool {
-	return l.stage == numStages
+	return l.stage >= l.NumStages()-1
 }
 
 func (l *Level) NextStage() {
@@ -46,55 +91,159 @@ func (l *Level) Reset() {
 	l.stage = 0
 }
 
-func (l *Level) getHitpoints(i int) int {
-	hp := sprites.HitpointIncrement
-	switch l.stage {
-	case 0:
-	case 1:
-		hp = hp + (hp * (i % 2))
-	case 2:
-		hp = hp + (hp * (i % 3))
-	default:
-		hp = hp + (hp * (i % 4))
+// SetEnemiesPerStage changes how many enemies every stage has to about n,
+// keeping the share each wave gets.
+func (l *Level) SetEnemiesPerStage(n int) {
+	for _, s := range l.stages {
+		total := 0
+		for _, w := range s.Waves {
+			total = total + w.Enemies
+		}
+		if total == 0 {
+			continue
+		}
+		for i := range s.Waves {
+			s.Waves[i].Enemies = s.Waves[i].Enemies * n / total
+		}
+	}
+}
+
+// PopulateEnemies starts the current stage, bringing in its boss or the
+// waves that arrive straight away. Nothing is placed within SafeRadius of
+// heroes.
+func (l *Level) PopulateEnemies(width, height int, heroes []geom.Vector, enemies *sprites.EnemyStore, rng *rand.Rand) {
+	l.wave = 0
+	l.stageTicks = 0
+	l.spawned = 0
+	if l.IsBossStage() {
+		arena := l.arena(width, height, l.boss.EnemyType(), heroes, rng)
+		l.points = append(l.points[:0], geom.Vector{X: float64(width) / 2, Y: float64(arena.FrameHeight) * l.boss.Scale / 2})
+		keepClear(l.points, &arena)
+		enemies.SpawnBoss(l.boss, int(l.points[0].X), int(l.points[0].Y), rng)
+		return
 	}
+	l.spawnWaves(width, height, heroes, enemies, rng)
+}
 
-	return hp
+// Update advances the stage one step, bringing in any waves that are due.
+func (l *Level) Update(width, height int, heroes []geom.Vector, enemies *sprites.EnemyStore, rng *rand.Rand) {
+	l.stageTicks = l.stageTicks + 1
+	l.spawnWaves(width, height, heroes, enemies, rng)
 }
 
-func (l *Level) PopulateEnemies(width, height int, enemies map[string]*sprites.Enemy) {
-	if l.stage == numStages {
-		enemies[uuid.NewString()] = sprites.NewEnemy(0, 0, 1000, true, l.enemyImage)
-	} else {
-		enemiesPerSide := l.GetNumEnemies() / 4
-		for i := 0 ; i < enemiesPerSide ; i++ {
-			x := 0
-			y := (i * (height / enemiesPerSide))
-			enemies[uuid.New().String()] = sprites.NewEnemy(x, y, l.getHitpoints(i), false, l.enemyImage)
-		}
- 		for i := 0 ; i < enemiesPerSide ; i++ {
-			x := (i * (width / enemiesPerSide))
-			y := 0
-			enemies[uuid.New().String()] = sprites.NewEnemy(x, y, l.getHitpoints(i), false, l.enemyImage)
+// Restart sends the living enemies back to where they arrived, as after a
+// hero loses a life. The heroes have moved since then, so any start that is
+// now within SafeRadius of one is moved away as new arrivals are.
+func (l *Level) Restart(width, height int, heroes []geom.Vector, enemies *sprites.EnemyStore, rng *rand.Rand) {
+	for _, e := range enemies.All() {
+		if !e.IsAlive() {
+			continue
 		}
-		for i := 0 ; i < enemiesPerSide ; i++ {
-			x := width - (l.enemyImage.Bounds().Dx() / 5)
-			y := (i * (height / enemiesPerSide))
-			enemies[uuid.New().String()] = sprites.NewEnemy(x, y, l.getHitpoints(i), false, l.enemyImage)
+		e.ToStart()
+		arena := l.arena(width, height, e.Type, heroes, rng)
+		l.points = append(l.points[:0], e.Position())
+		keepClear(l.points, &arena)
+		e.MoveStart(int(math.Round(l.points[0].X)), int(math.Round(l.points[0].Y)))
+		e.ToStart()
+	}
+}
+
+func (l *Level) arena(width, height int, t *sprites.EnemyType, heroes []geom.Vector, rng *rand.Rand) Arena {
+	_, imageHeight := images.Size(t.Image)
+	return Arena{
+		Width:       width,
+		Height:      height,
+		FrameWidth:  t.FrameWidth,
+		FrameHeight: imageHeight,
+		Heroes:      heroes,
+		Walls:       l.walls,
+		Rng:         rng,
+	}
+}
+
+func (l *Level) due(w Wave, enemies *sprites.EnemyStore) bool {
+	if w.Ticks == 0 && w.Cleared == 0 {
+		return true
+	}
+	if w.Ticks > 0 && l.stageTicks >= w.Ticks {
+		return true
+	}
+	if w.Cleared == 0 {
+		return false
+	}
+	if l.spawned == 0 {
+		return true
+	}
+	alive := 0
+	for _, e := range enemies.All() {
+		if e.IsAlive() {
+			alive = alive + 1
 		}
-		for i := 0 ; i < enemiesPerSide ; i++ {
-			x := (i * (width / enemiesPerSide))
-			y := height - l.enemyImage.Bounds().Dy()
-			enemies[uuid.New().String()] = sprites.NewEnemy(x, y, l.getHitpoints(i), false, l.enemyImage)
+	}
+	return float64(l.spawned-alive) >= w.Cleared*float64(l.spawned)
+}
+
+func (l *Level) spawnWaves(width, height int, heroes []geom.Vector, enemies *sprites.EnemyStore, rng *rand.Rand) {
+	if l.IsBossStage() {
+		return
+	}
+	stage := l.stages[l.stage]
+	for l.wave < len(stage.Waves) && l.due(stage.Waves[l.wave], enemies) {
+		w := stage.Waves[l.wave]
+		arena := l.arena(width, height, w.Enemy, heroes, rng)
+		l.points = w.Pattern.Place(l.points[:0], w.Enemies, &arena)
+		keepClear(l.points, &arena)
+		for i, p := range l.points {
+			hp := stage.Hitpoints.hitpoints(w.Enemy.Hitpoints, i, len(l.points))
+			enemies.Spawn(w.Enemy, int(math.Round(p.X)), int(math.Round(p.Y)), hp, rng)
 		}
- 	}
+		l.spawned = l.spawned + len(l.points)
+		l.wave = l.wave + 1
+	}
+}
+
+// Wave returns how many of the stage's waves have arrived.
+func (l *Level) Wave() int {
+	return l.wave
+}
+
+// Waves returns how many waves the stage has, which is none for the boss.
+func (l *Level) Waves() int {
+	if l.IsBossStage() {
+		return 0
+	}
+	return len(l.stages[l.stage].Waves)
+}
+
+// NextWaveTicks returns how long until the next wave arrives if nothing
+// brings it in sooner, or -1 when there is no timer on it.
+func (l *Level) NextWaveTicks() int64 {
+	if l.wave >= l.Waves() {
+		return -1
+	}
+	w := l.stages[l.stage].Waves[l.wave]
+	if w.Ticks == 0 {
+		return -1
+	}
+	return w.Ticks - l.stageTicks
+}
+
+// Cleared reports whether every wave of the stage has arrived and been
+// beaten.
+func (l *Level) Cleared(enemies *sprites.EnemyStore) bool {
+	return l.wave >= l.Waves() && enemies.Len() == 0
 }
 
-func NewLevel(name string, enemyImage *ebiten.Image,bgImage *ebiten.Image, numEnemies int) *Level {
+// NewLevel creates a level called name played on the bgImage background
+// with walls laid over it. walls may be nil for an open arena and boss may
+// be nil for a level without a boss fight.
+func NewLevel(name, bgImage string, walls *tilemap.Map, stages []Stage, boss *sprites.BossType) *Level {
 	return &Level{
-		name:       name,
-		bgImage:    bgImage,
-		enemyImage: enemyImage,
-		numEnemies: numEnemies,
-		stage:      0,
+		name:    name,
+		bgImage: bgImage,
+		walls:   walls,
+		stages:  stages,
+		boss:    boss,
+		stage:   0,
 	}
 }
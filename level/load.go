@@ -0,0 +1,274 @@
+package level
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/sprites"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+//go:embed levels/*.json
+var defaultLevels embed.FS
+
+// PatternDef is how a spawn pattern is written in a level file. Name is one
+// of edge, edges, corners, ring, line, outside, clusters or mix, and the
+// other fields are the settings of that pattern. Side is one of left, top,
+// right or bottom.
+type PatternDef struct {
+	Name     string       `json:"name"`
+	Side     string       `json:"side"`
+	Radius   float64      `json:"radius"`
+	From     geom.Vector  `json:"from"`
+	To       geom.Vector  `json:"to"`
+	Margin   float64      `json:"margin"`
+	Count    int          `json:"count"`
+	Patterns []PatternDef `json:"patterns"`
+}
+
+var sides = map[string]Side{
+	"left":   Left,
+	"top":    Top,
+	"right":  Right,
+	"bottom": Bottom,
+}
+
+func (d PatternDef) pattern() (Pattern, error) {
+	switch d.Name {
+	case "edge":
+		side, ok := sides[d.Side]
+		if !ok {
+			return nil, fmt.Errorf("unknown side %q", d.Side)
+		}
+		return Edge{Side: side}, nil
+	case "edges":
+		return Edges{}, nil
+	case "corners":
+		return Corners{}, nil
+	case "ring":
+		if d.Radius <= 0 {
+			return nil, fmt.Errorf("ring radius must be positive")
+		}
+		return Ring{Radius: d.Radius}, nil
+	case "line":
+		return Line{From: d.From, To: d.To}, nil
+	case "outside":
+		return RandomOutside{Margin: d.Margin}, nil
+	case "clusters":
+		if d.Count <= 0 {
+			return nil, fmt.Errorf("clusters count must be positive")
+		}
+		return Clusters{Count: d.Count, Radius: d.Radius}, nil
+	case "mix":
+		if len(d.Patterns) == 0 {
+			return nil, fmt.Errorf("mix without patterns")
+		}
+		mix := Mix{}
+		for _, p := range d.Patterns {
+			pattern, err := p.pattern()
+			if err != nil {
+				return nil, err
+			}
+			mix = append(mix, pattern)
+		}
+		return mix, nil
+	}
+	return nil, fmt.Errorf("unknown pattern %q", d.Name)
+}
+
+// WaveDef is how a wave is written in a level file. Enemy is the name of an
+// enemy type and defaults to the level's Enemy.
+type WaveDef struct {
+	Enemy   string     `json:"enemy"`
+	Count   int        `json:"count"`
+	Pattern PatternDef `json:"pattern"`
+	Ticks   int64      `json:"ticks"`
+	Cleared float64    `json:"cleared"`
+}
+
+// StageDef is how a stage is written in a level file. Hitpoints defaults to
+// every enemy having its type's hitpoints.
+type StageDef struct {
+	Hitpoints Curve     `json:"hitpoints"`
+	Waves     []WaveDef `json:"waves"`
+}
+
+// MapDef is how an arena's walls are written in a level file: rows of
+// tiles TileSize pixels across, as tilemap.Parse reads them.
+type MapDef struct {
+	TileSize int      `json:"tileSize"`
+	Rows     []string `json:"rows"`
+}
+
+// LevelDef is how a level is written in a level file. Background is the
+// name of a built in image or a PNG file next to the level file, and Boss
+// is the name of a boss type, or empty for a level without a boss fight.
+// A level without a Map is fought in an open arena.
+type LevelDef struct {
+	Name       string     `json:"name"`
+	Background string     `json:"background"`
+	Map        *MapDef    `json:"map"`
+	Enemy      string     `json:"enemy"`
+	Stages     []StageDef `json:"stages"`
+	Boss       string     `json:"boss"`
+}
+
+func (d *LevelDef) level(enemyTypes sprites.EnemyTypes, bossTypes sprites.BossTypes) (*Level, error) {
+	if d.Name == "" {
+		return nil, fmt.Errorf("level without a name")
+	}
+	var boss *sprites.BossType
+	if d.Boss != "" {
+		var ok bool
+		boss, ok = bossTypes[d.Boss]
+		if !ok {
+			return nil, fmt.Errorf("level %s: unknown boss %q", d.Name, d.Boss)
+		}
+	}
+	if len(d.Stages) == 0 && boss == nil {
+		return nil, fmt.Errorf("level %s: needs a stage or a boss", d.Name)
+	}
+	var walls *tilemap.Map
+	if d.Map != nil {
+		var err error
+		walls, err = tilemap.Parse(d.Map.Rows, d.Map.TileSize)
+		if err != nil {
+			return nil, fmt.Errorf("level %s: %w", d.Name, err)
+		}
+	}
+	stages := []Stage{}
+	for i, sd := range d.Stages {
+		stage := Stage{Hitpoints: sd.Hitpoints}
+		if stage.Hitpoints.From == 0 && stage.Hitpoints.To == 0 {
+			stage.Hitpoints = Curve{From: 1, To: 1}
+		}
+		if len(sd.Waves) == 0 {
+			return nil, fmt.Errorf("level %s: stage %d has no waves", d.Name, i+1)
+		}
+		for j, wd := range sd.Waves {
+			name := wd.Enemy
+			if name == "" {
+				name = d.Enemy
+			}
+			enemy, ok := enemyTypes[name]
+			if !ok {
+				return nil, fmt.Errorf("level %s: stage %d wave %d: unknown enemy type %q", d.Name, i+1, j+1, name)
+			}
+			pattern, err := wd.Pattern.pattern()
+			if err != nil {
+				return nil, fmt.Errorf("level %s: stage %d wave %d: %w", d.Name, i+1, j+1, err)
+			}
+			if wd.Count < 0 || wd.Ticks < 0 || wd.Cleared < 0 || wd.Cleared > 1 {
+				return nil, fmt.Errorf("level %s: stage %d wave %d: count and ticks must not be negative and cleared must be from 0 to 1", d.Name, i+1, j+1)
+			}
+			stage.Waves = append(stage.Waves, Wave{
+				Enemy:   enemy,
+				Enemies: wd.Count,
+				Pattern: pattern,
+				Ticks:   wd.Ticks,
+				Cleared: wd.Cleared,
+			})
+		}
+		stages = append(stages, stage)
+	}
+	return NewLevel(d.Name, d.Background, walls, stages, boss), nil
+}
+
+// Load reads every .json level file in fsys, in order of file name.
+// Backgrounds that are not built into the game are read from fsys too.
+func Load(fsys fs.FS, enemyTypes sprites.EnemyTypes, bossTypes sprites.BossTypes) ([]*Level, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	levels := []*Level{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		d := &LevelDef{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if !images.Has(d.Background) {
+			image, err := fs.ReadFile(fsys, d.Background)
+			if err != nil {
+				return nil, fmt.Errorf("%s: background: %w", entry.Name(), err)
+			}
+			if err := images.Add(d.Background, image); err != nil {
+				return nil, err
+			}
+		}
+		l, err := d.level(enemyTypes, bossTypes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		levels = append(levels, l)
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("no level files")
+	}
+	return levels, nil
+}
+
+// LoadDir reads the level files in dir.
+func LoadDir(dir string, enemyTypes sprites.EnemyTypes, bossTypes sprites.BossTypes) ([]*Level, error) {
+	return Load(os.DirFS(dir), enemyTypes, bossTypes)
+}
+
+// LoadWithDefaults returns the levels built into the game followed by the
+// levels in dir. A level in dir with the name of a built in level takes its
+// place instead. Without a dir only the built in levels are returned.
+func LoadWithDefaults(dir string, enemyTypes sprites.EnemyTypes, bossTypes sprites.BossTypes) ([]*Level, error) {
+	levels := DefaultLevels(enemyTypes, bossTypes)
+	if dir == "" {
+		return levels, nil
+	}
+	extra, err := LoadDir(dir, enemyTypes, bossTypes)
+	if err != nil {
+		return nil, err
+	}
+	return merge(levels, extra), nil
+}
+
+// merge adds extra to levels, replacing levels of the same name.
+func merge(levels, extra []*Level) []*Level {
+	for _, e := range extra {
+		replaced := false
+		for i, l := range levels {
+			if l.GetName() == e.GetName() {
+				levels[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			levels = append(levels, e)
+		}
+	}
+	return levels
+}
+
+// DefaultLevels returns the levels built into the game.
+func DefaultLevels(enemyTypes sprites.EnemyTypes, bossTypes sprites.BossTypes) []*Level {
+	fsys, err := fs.Sub(defaultLevels, "levels")
+	if err != nil {
+		log.Fatal(err)
+	}
+	levels, err := Load(fsys, enemyTypes, bossTypes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return levels
+}
@@ -0,0 +1,86 @@
+package level
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+func types() (sprites.EnemyTypes, sprites.BossTypes) {
+	enemyTypes := sprites.DefaultEnemyTypes()
+	return enemyTypes, sprites.DefaultBossTypes(enemyTypes)
+}
+
+func names(levels []*Level) []string {
+	names := []string{}
+	for _, l := range levels {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+func TestLoadWithDefaults(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.json": `{"name": "Goblins in the grass", "background": "grass", "boss": "goblinKing"}`,
+		"b.json": `{"name": "The bone pit", "background": "stone", "enemy": "skeleton",
+			"stages": [{"waves": [{"count": 5, "pattern": {"name": "corners"}}]}]}`,
+		"notes.txt": "not a level",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enemyTypes, bossTypes := types()
+
+	defaults, err := LoadWithDefaults("", enemyTypes, bossTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Goblins in the grass", "Skeletons on the stone"}; !reflect.DeepEqual(names(defaults), want) {
+		t.Errorf("built in levels %v, want %v", names(defaults), want)
+	}
+
+	levels, err := LoadWithDefaults(dir, enemyTypes, bossTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Goblins in the grass", "Skeletons on the stone", "The bone pit"}; !reflect.DeepEqual(names(levels), want) {
+		t.Errorf("levels %v, want %v", names(levels), want)
+	}
+	// the grass level from the directory is only a boss fight
+	if levels[0].NumStages() != 1 || !levels[0].IsBossStage() {
+		t.Errorf("the built in grass level was not replaced")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		want  string
+	}{
+		{"bad json", `{"name": `, "a.json"},
+		{"no name", `{"background": "grass", "boss": "goblinKing"}`, "level without a name"},
+		{"nothing to fight", `{"name": "x", "background": "grass"}`, "needs a stage or a boss"},
+		{"unknown boss", `{"name": "x", "background": "grass", "boss": "nobody"}`, `unknown boss "nobody"`},
+		{"unknown enemy", `{"name": "x", "background": "grass", "stages": [{"waves": [{"enemy": "ghost", "count": 1, "pattern": {"name": "edges"}}]}]}`, `unknown enemy type "ghost"`},
+		{"unknown pattern", `{"name": "x", "background": "grass", "enemy": "goblin", "stages": [{"waves": [{"count": 1, "pattern": {"name": "spiral"}}]}]}`, `unknown pattern "spiral"`},
+		{"missing background", `{"name": "x", "background": "moon.png", "boss": "goblinKing"}`, "background"},
+		{"ragged map", `{"name": "x", "background": "grass", "boss": "goblinKing", "map": {"tileSize": 40, "rows": ["...", ".."]}}`, "map row 2"},
+	}
+	enemyTypes, bossTypes := types()
+	for _, tt := range tests {
+		fsys := fstest.MapFS{"a.json": {Data: []byte(tt.level)}}
+		_, err := Load(fsys, enemyTypes, bossTypes)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package level
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+const (
+	// SafeRadius is how close to a hero an enemy may be placed.
+	SafeRadius float64 = 150
+
+	// awayTurns is how many steps either way round a hero keepClear looks
+	// for somewhere on screen to move an enemy to
+	awayTurns = 16
+)
+
+// Arena is what a Pattern needs to know to place enemies. FrameWidth and
+// FrameHeight are the size of the enemy being placed.
+type Arena struct {
+	Width, Height           int
+	FrameWidth, FrameHeight int
+	Heroes                  []geom.Vector
+	Walls                   *tilemap.Map
+	Rng                     *rand.Rand
+}
+
+// inset returns the rectangle enemy centers are kept in so that a whole
+// enemy shows on screen.
+func (a *Arena) inset() (geom.Vector, geom.Vector) {
+	min := geom.Vector{X: float64(a.FrameWidth) / 2, Y: float64(a.FrameHeight) / 2}
+	max := geom.Vector{X: float64(a.Width) - min.X, Y: float64(a.Height) - min.Y}
+	return min, max
+}
+
+// contain moves p into the rectangle returned by inset.
+func (a *Arena) contain(p geom.Vector) geom.Vector {
+	min, max := a.inset()
+	return geom.Vector{X: math.Max(min.X, math.Min(p.X, max.X)), Y: math.Max(min.Y, math.Min(p.Y, max.Y))}
+}
+
+// onScreen reports whether a whole enemy centered on p shows on screen.
+func (a *Arena) onScreen(p geom.Vector) bool {
+	return a.contain(p) == p
+}
+
+// Pattern places the enemies of a wave. Place appends where each of n
+// enemies goes to points.
+type Pattern interface {
+	Place(points []geom.Vector, n int, a *Arena) []geom.Vector
+}
+
+// Side is one edge of the arena.
+type Side int
+
+const (
+	Left Side = iota
+	Top
+	Right
+	Bottom
+)
+
+// Edge spreads enemies evenly along one side of the arena.
+type Edge struct {
+	Side Side
+}
+
+func (e Edge) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	min, max := a.inset()
+	from, to := min, max
+	switch e.Side {
+	case Left:
+		to.X = min.X
+	case Top:
+		to.Y = min.Y
+	case Right:
+		from.X = max.X
+	case Bottom:
+		from.Y = max.Y
+	}
+	return Line{}.between(points, n, from, to)
+}
+
+// Edges spreads enemies evenly all the way round the arena.
+type Edges struct{}
+
+func (Edges) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	min, max := a.inset()
+	width := max.X - min.X
+	height := max.Y - min.Y
+	perimeter := 2 * (width + height)
+	for i := 0; i < n; i++ {
+		d := perimeter * (float64(i) + 0.5) / float64(n)
+		var p geom.Vector
+		switch {
+		case d < width:
+			p = geom.Vector{X: min.X + d, Y: min.Y}
+		case d < width+height:
+			p = geom.Vector{X: max.X, Y: min.Y + (d - width)}
+		case d < (2*width)+height:
+			p = geom.Vector{X: max.X - (d - width - height), Y: max.Y}
+		default:
+			p = geom.Vector{X: min.X, Y: max.Y - (d - (2 * width) - height)}
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// Corners shares enemies between the four corners, stacking them inwards
+// along the diagonal.
+type Corners struct{}
+
+func (Corners) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	min, max := a.inset()
+	step := geom.Vector{X: float64(a.FrameWidth), Y: float64(a.FrameHeight)}
+	corners := [4]geom.Vector{min, {X: max.X, Y: min.Y}, max, {X: min.X, Y: max.Y}}
+	inwards := [4]geom.Vector{{X: 1, Y: 1}, {X: -1, Y: 1}, {X: -1, Y: -1}, {X: 1, Y: -1}}
+	for i := 0; i < n; i++ {
+		c := i % 4
+		k := float64(i / 4)
+		points = append(points, corners[c].Add(geom.Vector{X: inwards[c].X * step.X * k, Y: inwards[c].Y * step.Y * k}))
+	}
+	return points
+}
+
+// Ring surrounds the heroes with enemies Radius away from the middle of
+// them, moving any that would be off screen back on.
+type Ring struct {
+	Radius float64
+}
+
+func (r Ring) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	center := geom.Vector{X: float64(a.Width) / 2, Y: float64(a.Height) / 2}
+	if len(a.Heroes) > 0 {
+		center = geom.Vector{}
+		for _, h := range a.Heroes {
+			center = center.Add(h)
+		}
+		center = center.Scale(1 / float64(len(a.Heroes)))
+	}
+	offset := a.Rng.Float64() * 2 * math.Pi
+	for i := 0; i < n; i++ {
+		angle := offset + (2 * math.Pi * float64(i) / float64(n))
+		points = append(points, a.contain(center.Add(geom.FromAngle(angle).Scale(r.Radius))))
+	}
+	return points
+}
+
+// Line spreads enemies evenly from From to To, which are given as fractions
+// of the arena's width and height.
+type Line struct {
+	From, To geom.Vector
+}
+
+func (l Line) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	size := geom.Vector{X: float64(a.Width), Y: float64(a.Height)}
+	from := geom.Vector{X: l.From.X * size.X, Y: l.From.Y * size.Y}
+	to := geom.Vector{X: l.To.X * size.X, Y: l.To.Y * size.Y}
+	return l.between(points, n, from, to)
+}
+
+func (Line) between(points []geom.Vector, n int, from, to geom.Vector) []geom.Vector {
+	for i := 0; i < n; i++ {
+		points = append(points, from.Add(to.Sub(from).Scale((float64(i)+0.5)/float64(n))))
+	}
+	return points
+}
+
+// RandomOutside places enemies at random just off screen, Margin beyond the
+// edge, so they walk in from wherever they happen to be.
+type RandomOutside struct {
+	Margin float64
+}
+
+func (r RandomOutside) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	width := float64(a.Width) + (2 * r.Margin)
+	height := float64(a.Height) + (2 * r.Margin)
+	for i := 0; i < n; i++ {
+		d := a.Rng.Float64() * 2 * (width + height)
+		var p geom.Vector
+		switch {
+		case d < width:
+			p = geom.Vector{X: d, Y: 0}
+		case d < width+height:
+			p = geom.Vector{X: width, Y: d - width}
+		case d < (2*width)+height:
+			p = geom.Vector{X: d - width - height, Y: height}
+		default:
+			p = geom.Vector{X: 0, Y: d - (2 * width) - height}
+		}
+		points = append(points, p.Sub(geom.Vector{X: r.Margin, Y: r.Margin}))
+	}
+	return points
+}
+
+// Clusters gathers enemies into Count packs at random places in the arena,
+// each within Radius of the middle of its pack but never off screen.
+type Clusters struct {
+	Count  int
+	Radius float64
+}
+
+func (c Clusters) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	if n == 0 {
+		return points
+	}
+	count := c.Count
+	if count < 1 {
+		count = 1
+	} else if count > n {
+		count = n
+	}
+	min, max := a.inset()
+	start := len(points)
+	for i := 0; i < count; i++ {
+		points = append(points, geom.Vector{
+			X: min.X + (a.Rng.Float64() * (max.X - min.X)),
+			Y: min.Y + (a.Rng.Float64() * (max.Y - min.Y)),
+		})
+	}
+	for i := count; i < n; i++ {
+		center := points[start+(i%count)]
+		offset := geom.FromAngle(a.Rng.Float64() * 2 * math.Pi).Scale(math.Sqrt(a.Rng.Float64()) * c.Radius)
+		points = append(points, a.contain(center.Add(offset)))
+	}
+	return points
+}
+
+// Mix shares enemies as evenly as it can between Patterns.
+type Mix []Pattern
+
+func (m Mix) Place(points []geom.Vector, n int, a *Arena) []geom.Vector {
+	if len(m) == 0 {
+		return points
+	}
+	for i, p := range m {
+		share := n / len(m)
+		if i < n%len(m) {
+			share = share + 1
+		}
+		points = p.Place(points, share, a)
+	}
+	return points
+}
+
+func (a *Arena) clear(p geom.Vector) bool {
+	for _, h := range a.Heroes {
+		if p.Sub(h).Length() < SafeRadius {
+			return false
+		}
+	}
+	return true
+}
+
+// keepClear moves any point within SafeRadius of a hero out past it,
+// falling back to the corner furthest from the heroes when they are so
+// close together that moving away from one brings it too close to another.
+// Points placed on screen are kept on screen. Points that end up inside
+// walls move to the nearest open tile that is still clear of the heroes.
+func keepClear(points []geom.Vector, a *Arena) {
+	for i := range points {
+		onScreen := a.onScreen(points[i])
+		for pass := 0; pass < 4 && !a.clear(points[i]); pass++ {
+			for _, h := range a.Heroes {
+				away := points[i].Sub(h)
+				if away.Length() >= SafeRadius {
+					continue
+				}
+				if away.IsZero() {
+					away = geom.FromAngle(a.Rng.Float64() * 2 * math.Pi)
+				}
+				points[i] = a.awayFrom(h, away, onScreen)
+			}
+		}
+		if !a.clear(points[i]) || (onScreen && !a.onScreen(points[i])) {
+			points[i] = a.furthestCorner()
+		}
+		points[i] = a.Walls.NearestOpen(points[i], a.clear)
+	}
+}
+
+// awayFrom returns the point just beyond SafeRadius of hero h in the
+// direction away, or, when that is off screen and onScreen is set, in the
+// nearest direction to it that is not.
+func (a *Arena) awayFrom(h, away geom.Vector, onScreen bool) geom.Vector {
+	// a pixel further out so rounding the enemy onto whole pixels cannot
+	// bring it back within SafeRadius
+	out := away.Normalize().Scale(SafeRadius + 1)
+	for turn := 0; turn <= 2*awayTurns; turn++ {
+		// straight away first, then a step further round each way in turn
+		angle := math.Pi * float64((turn+1)/2) / awayTurns
+		if turn%2 == 1 {
+			angle = -angle
+		}
+		if p := h.Add(out.Rotate(angle)); !onScreen || a.onScreen(p) {
+			return p
+		}
+	}
+	return h.Add(out)
+}
+
+func (a *Arena) furthestCorner() geom.Vector {
+	min, max := a.inset()
+	var furthest geom.Vector
+	best := -1.0
+	for _, c := range [4]geom.Vector{min, {X: max.X, Y: min.Y}, max, {X: min.X, Y: max.Y}} {
+		nearest := math.MaxFloat64
+		for _, h := range a.Heroes {
+			nearest = math.Min(nearest, c.Sub(h).Length())
+		}
+		if nearest > best {
+			furthest = c
+			best = nearest
+		}
+	}
+	return furthest
+}
@@ -0,0 +1,85 @@
+package level
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+const (
+	arenaWidth  int = 1000
+	arenaHeight int = 800
+)
+
+var patterns = []struct {
+	name    string
+	pattern Pattern
+}{
+	{"left edge", Edge{Side: Left}},
+	{"top edge", Edge{Side: Top}},
+	{"right edge", Edge{Side: Right}},
+	{"bottom edge", Edge{Side: Bottom}},
+	{"edges", Edges{}},
+	{"corners", Corners{}},
+	{"ring", Ring{Radius: 250}},
+	{"line", Line{From: geom.Vector{X: 0.1, Y: 0.5}, To: geom.Vector{X: 0.9, Y: 0.5}}},
+	{"clusters", Clusters{Count: 3, Radius: 120}},
+	{"mix", Mix{Edge{Side: Top}, Corners{}, Ring{Radius: 200}}},
+}
+
+// heroes are the places heroes stand while a wave arrives, including
+// against a wall and in a corner where patterns want to put enemies.
+var heroes = [][]geom.Vector{
+	nil,
+	{{X: 500, Y: 400}},
+	{{X: 470, Y: 400}, {X: 530, Y: 400}},
+	{{X: 20, Y: 400}},
+	{{X: 20, Y: 20}},
+	{{X: 980, Y: 780}, {X: 500, Y: 780}},
+}
+
+func TestPatternsKeepClear(t *testing.T) {
+	for _, p := range patterns {
+		for i, h := range heroes {
+			for _, n := range []int{1, 7, 40} {
+				name := fmt.Sprintf("%s, heroes %d, %d enemies", p.name, i, n)
+				a := &Arena{Width: arenaWidth, Height: arenaHeight, FrameWidth: 32, FrameHeight: 32, Heroes: h, Rng: rand.New(rand.NewSource(int64(n)))}
+				points := p.pattern.Place(nil, n, a)
+				keepClear(points, a)
+				if len(points) != n {
+					t.Errorf("%s: placed %d enemies", name, len(points))
+				}
+				for _, pt := range points {
+					if pt.X < 0 || pt.Y < 0 || pt.X > float64(arenaWidth) || pt.Y > float64(arenaHeight) {
+						t.Errorf("%s: enemy placed outside the arena at %v", name, pt)
+					}
+					if !a.clear(pt) {
+						t.Errorf("%s: enemy placed within %v of a hero at %v", name, SafeRadius, pt)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRandomOutsideKeepsClear(t *testing.T) {
+	margin := 50.0
+	for i, h := range heroes {
+		a := &Arena{Width: arenaWidth, Height: arenaHeight, FrameWidth: 32, FrameHeight: 32, Heroes: h, Rng: rand.New(rand.NewSource(1))}
+		points := RandomOutside{Margin: margin}.Place(nil, 40, a)
+		for _, pt := range points {
+			onEdge := pt.X == -margin || pt.Y == -margin || pt.X == float64(arenaWidth)+margin || pt.Y == float64(arenaHeight)+margin
+			if !onEdge {
+				t.Errorf("heroes %d: enemy placed at %v, not %v off screen", i, pt, margin)
+			}
+		}
+		keepClear(points, a)
+		for _, pt := range points {
+			if a.onScreen(pt) || !a.clear(pt) {
+				t.Errorf("heroes %d: enemy kept clear to %v", i, pt)
+			}
+		}
+	}
+}
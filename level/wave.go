@@ -0,0 +1,17 @@
+package level
+
+import (
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+// Wave is a group of Enemies of the Enemy type that arrive together, placed
+// by Pattern. A wave arrives Ticks into the stage or once Cleared, a
+// fraction, of the enemies that came before it are dead, whichever happens
+// first. A wave with neither arrives along with the wave before it.
+type Wave struct {
+	Enemy   *sprites.EnemyType
+	Enemies int
+	Pattern Pattern
+	Ticks   int64
+	Cleared float64
+}
@@ -0,0 +1,122 @@
+package level
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+// waveLevel returns a level with one stage of five waves: two arriving at
+// once, one 10 ticks in, one once half the enemies before it are dead or
+// 100 ticks in, and a last one 200 ticks in.
+func waveLevel() *Level {
+	goblin := sprites.DefaultEnemyTypes()["goblin"]
+	stages := []Stage{
+		{
+			Hitpoints: Curve{From: 1, To: 1},
+			Waves: []Wave{
+				{Enemy: goblin, Enemies: 2, Pattern: Edge{Side: Left}},
+				{Enemy: goblin, Enemies: 1, Pattern: Edge{Side: Right}},
+				{Enemy: goblin, Enemies: 2, Pattern: Edge{Side: Top}, Ticks: 10},
+				{Enemy: goblin, Enemies: 2, Pattern: Edge{Side: Bottom}, Ticks: 100, Cleared: 0.5},
+				{Enemy: goblin, Enemies: 1, Pattern: Corners{}, Ticks: 200},
+			},
+		},
+	}
+	return NewLevel("waves", images.Grass, nil, stages, nil)
+}
+
+type arrival struct {
+	tick          int64
+	wave, enemies int
+}
+
+type waveRun struct {
+	level   *Level
+	enemies *sprites.EnemyStore
+	rng     *rand.Rand
+	tick    int64
+}
+
+func newWaveRun() *waveRun {
+	r := &waveRun{level: waveLevel(), enemies: sprites.NewEnemyStore(), rng: rand.New(rand.NewSource(1))}
+	r.level.PopulateEnemies(1000, 1000, nil, r.enemies, r.rng)
+	return r
+}
+
+// run updates the level until tick, killing kill[t] living enemies just
+// before tick t, and returns when each wave arrived and how many enemies
+// there were then.
+func (r *waveRun) run(until int64, kill map[int64]int) []arrival {
+	arrivals := []arrival{}
+	for r.tick < until {
+		r.tick = r.tick + 1
+		for _, e := range r.enemies.All() {
+			if kill[r.tick] > 0 && e.IsAlive() {
+				e.Shot(e.Type.Hitpoints, &sprites.Sprite{})
+				kill[r.tick] = kill[r.tick] - 1
+			}
+		}
+		wave := r.level.Wave()
+		r.level.Update(1000, 1000, nil, r.enemies, r.rng)
+		if r.level.Wave() != wave {
+			arrivals = append(arrivals, arrival{r.tick, r.level.Wave(), r.enemies.Len()})
+		}
+	}
+	return arrivals
+}
+
+func TestWaveTiming(t *testing.T) {
+	tests := []struct {
+		name string
+		kill map[int64]int
+		want []arrival
+	}{
+		{"on time", nil, []arrival{{10, 3, 5}, {100, 4, 7}, {200, 5, 8}}},
+		{"not enough killed", map[int64]int{20: 2}, []arrival{{10, 3, 5}, {100, 4, 7}, {200, 5, 8}}},
+		{"half killed", map[int64]int{20: 2, 30: 1}, []arrival{{10, 3, 5}, {30, 4, 7}, {200, 5, 8}}},
+		// the fourth wave is already due when the third arrives
+		{"killed early", map[int64]int{5: 3}, []arrival{{10, 4, 7}, {200, 5, 8}}},
+	}
+	for _, tt := range tests {
+		r := newWaveRun()
+		if r.level.Wave() != 2 || r.enemies.Len() != 3 {
+			t.Fatalf("%s: %d waves and %d enemies at the start, want 2 and 3", tt.name, r.level.Wave(), r.enemies.Len())
+		}
+		if got := r.run(300, tt.kill); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: waves arrived %v, want %v", tt.name, got, tt.want)
+		}
+		if r.level.NextWaveTicks() != -1 || r.level.Cleared(r.enemies) {
+			t.Errorf("%s: the stage is over or has waves to come after the last wave", tt.name)
+		}
+	}
+}
+
+func TestWavesAfterRestart(t *testing.T) {
+	r := newWaveRun()
+	r.run(50, nil)
+	if r.level.NextWaveTicks() != 50 {
+		t.Errorf("next wave in %d ticks, want 50", r.level.NextWaveTicks())
+	}
+
+	// losing a life sends enemies back but keeps the stage's clock
+	r.level.Restart(1000, 1000, nil, r.enemies, r.rng)
+	if r.level.Wave() != 3 || r.enemies.Len() != 5 || r.level.NextWaveTicks() != 50 {
+		t.Errorf("after a restart %d waves, %d enemies and the next wave in %d ticks, want 3, 5 and 50",
+			r.level.Wave(), r.enemies.Len(), r.level.NextWaveTicks())
+	}
+	if got, want := r.run(300, nil), []arrival{{100, 4, 7}, {200, 5, 8}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("waves arrived %v after a restart, want %v", got, want)
+	}
+
+	// starting the stage again starts its waves again
+	r.enemies.Clear()
+	r.level.PopulateEnemies(1000, 1000, nil, r.enemies, r.rng)
+	r.tick = 0
+	if got, want := r.run(300, nil), []arrival{{10, 3, 5}, {100, 4, 7}, {200, 5, 8}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("waves arrived %v after starting again, want %v", got, want)
+	}
+}
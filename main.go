@@ -1,312 +1,294 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"image/color"
+	"io"
 	"log"
-	"math"
+	"os"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 
 	"github.com/markrzasa/arrowsaway/fonts"
+	"github.com/markrzasa/arrowsaway/game"
 	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/input/device"
 	"github.com/markrzasa/arrowsaway/level"
+	"github.com/markrzasa/arrowsaway/render"
+	"github.com/markrzasa/arrowsaway/replay"
 	"github.com/markrzasa/arrowsaway/sprites"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 )
 
-const (
-	milliBetweenShots = 250
-)
-
-type gameState int
-const (
-	NoClicker gameState = iota
-	Running
-	NextStage
-	LostLife
-	GameOver
-	Winner
-)
-
 type ArrowsAway struct {
 	height, width int
 
-	state gameState
-
-	gamepadIdsBuffer []ebiten.GamepadID
-	gamepadIds       map[ebiten.GamepadID]bool
-
-	hero *sprites.Hero
-
-	levelIndex int
-	levels []*level.Level
+	world *game.World
 
-	enemies map[string]*sprites.Enemy
+	// noClicker is set while there is no device the game can be played with
+	noClicker bool
 
-	arrows map[string]*sprites.Arrow
+	gamepadIdsBuffer []ebiten.GamepadID
+	gamepads         map[ebiten.GamepadID]int
+	sources          map[int]input.Source
+	keyboard         input.Source
+	keyboardPlayer   int
 
-	lastShotMilli int64
+	// frame collects everything done to the world during an update so it can
+	// be recorded
+	frame    replay.Frame
+	recorder *replay.Recorder
 
-	score int64
+	playback *replay.Reader
+	frames   int64
+	diverged error
 
-	lives int
+	enemyTypes sprites.EnemyTypes
+	bossTypes  sprites.BossTypes
+	levelsDir  string
 
 	font font.Face
 }
 
-func (g *ArrowsAway) updateGamepads() {
-	g.gamepadIdsBuffer = inpututil.AppendJustConnectedGamepadIDs(g.gamepadIdsBuffer[:0])
-	for _, id := range g.gamepadIdsBuffer {
-		g.gamepadIds[id] = true
-	}
-	for id := range g.gamepadIds {
-		if inpututil.IsGamepadJustDisconnected(id) {
-			delete(g.gamepadIds, id)
-		}
+var errReplayDone = errors.New("replay finished")
+
+func (g *ArrowsAway) Update() error {
+	if g.playback != nil {
+		return g.replayFrame()
 	}
-}
 
-func (g *ArrowsAway) isPadButtonPressed() bool {
-	pressed := false
-	for id := range g.gamepadIds {
-		for b := ebiten.StandardGamepadButtonRightBottom; b < ebiten.StandardGamepadButtonMax; b++ {
-			if ebiten.IsStandardGamepadButtonPressed(id, b) {
-				pressed = true
-				break
-			}
-		}
+	g.frame.Intents = g.updatePlayers()
+
+	if !g.hasInput() {
+		g.noClicker = true
+		g.world.Pause()
+		g.event(replay.Event{Kind: replay.Pause})
+		g.frame.Idle = true
+	} else {
+		g.noClicker = false
+		g.world.Update(g.frame.Intents)
 	}
 
-	return pressed
+	return g.record()
 }
 
-func (g *ArrowsAway) hitEnemy(a *sprites.Arrow) bool {
-	hitWhileAlive := false
-	hit := false
-	for _, e := range g.enemies {
-		hitWhileAlive, hit = e.IsHit(a, g.hero.Sprite)
-		if hitWhileAlive {
-			g.score = g.score + 10
-		}	
-	}
-	return hit
+func (g *ArrowsAway) event(e replay.Event) {
+	g.frame.Events = append(g.frame.Events, e)
 }
 
-func (g *ArrowsAway) updateHero() {
-	for _, e := range g.enemies {
-		if e.IsAlive() && e.Sprite.Intersect(g.hero.Sprite) {
-			g.lives = g.lives - 1
-			if g.lives == 0 {
-				g.state = GameOver
-			} else {
-				g.state = LostLife
-			}
-			break
-		}
+func (g *ArrowsAway) record() error {
+	var err error
+	if g.recorder != nil {
+		err = g.recorder.Record(&g.frame, g.world)
 	}
+	g.frame = replay.Frame{}
+	return err
 }
 
-func (g *ArrowsAway) updateArrows() {
-	for id := range g.gamepadIds {
-		x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickHorizontal)
-		y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickVertical)
-		if math.Abs(x) > 0.5 || math.Abs(y) > 0.5 {
-			if g.lastShotMilli == 0 || (time.Now().UnixMilli() >= (g.lastShotMilli + milliBetweenShots)) {
-				heroBounds := g.hero.Sprite.Bounds()
-				endX := heroBounds.Min.X + int(float64(g.width / 2) * x)
-				endY := heroBounds.Min.Y + int(float64(g.height / 2) * y)
-				arrow := sprites.NewArrow(g.hero.Sprite.X, g.hero.Sprite.Y, endX, endY)
-				g.arrows[arrow.Id] = arrow
-			}
-		} else {
-			g.lastShotMilli = 0
-		}
+func (g *ArrowsAway) replayFrame() error {
+	f, err := g.playback.Next()
+	if err == io.EOF {
+		return errReplayDone
+	} else if err != nil {
+		return err
 	}
-
-	for id, a := range g.arrows {
-		if g.hitEnemy(a) {
-			delete(g.arrows, id)
-		} else if a.IsOffScreen(g.width, g.height) {
-			delete(g.arrows, id)
-		}
+	g.frames = g.frames + 1
+	if err := replay.Apply(g.world, f); err != nil {
+		return err
 	}
-
-	for _, a := range(g.arrows) {
-		a.Update()
+	if err := replay.Check(g.world, g.frames, f); err != nil && g.diverged == nil {
+		log.Print(err)
+		g.diverged = err
 	}
+	return nil
 }
 
-func (g *ArrowsAway) updateEnemies() {
-	for id, e := range g.enemies {
-		if e.IsBuried() {
-			delete(g.enemies, id)
-		}
+func (g *ArrowsAway) drawCentered(screen *ebiten.Image, y int, t []string) {
+	for i, s := range t {
+		r := text.BoundString(g.font, s)
+		text.Draw(
+			screen,
+			s,
+			g.font,
+			(g.width/2)-(r.Dx()/2), y+(i*(r.Dy()+10)), color.RGBA{0x00, 0x00, 0x00, 0xff})
 	}
+}
 
-	for _, e := range g.enemies {
-		e.Update(g.width, g.height, g.hero.Sprite)
+func (g *ArrowsAway) scoreLines() []string {
+	lines := []string{fmt.Sprintf("Seed: %d", g.world.Seed())}
+	for _, p := range g.world.Players() {
+		lines = append(lines, fmt.Sprintf("P%d: %d", p.Id+1, p.Score))
 	}
+	return lines
 }
 
-func (g *ArrowsAway) updateLevel() {
-	if len(g.enemies) == 0 {
-		level := g.levels[g.levelIndex]
-		if level.Complete() {
-			g.levelIndex = g.levelIndex + 1
-			if g.levelIndex == len(g.levels) {
-				g.state = Winner
-			} else {
-				g.levels[g.levelIndex].PopulateEnemies(g.width, g.height, g.enemies)
-				g.state = NextStage
-			}
-		} else {
-			level.NextStage()
-			level.PopulateEnemies(g.width, g.height, g.enemies)
-			g.state = NextStage
+func (g *ArrowsAway) drawScores(screen *ebiten.Image) {
+	lifeImage := render.Texture(images.Life)
+	op := &ebiten.DrawImageOptions{}
+	for row, p := range g.world.Players() {
+		y := g.height - 20 - (row * (lifeImage.Bounds().Dy() + 10))
+		text.Draw(
+			screen,
+			fmt.Sprintf("P%d Score: %d", p.Id+1, p.Score),
+			g.font,
+			10, y, color.RGBA{0x00, 0x00, 0x00, 0xff})
+		for i := 0; i < p.Lives; i++ {
+			op.GeoM.Reset()
+			op.GeoM.Translate(float64(g.width-10-(lifeImage.Bounds().Dx()*(i+1))), float64(y-lifeImage.Bounds().Dy()))
+			op.ColorM.Reset()
+			render.Tint(op, p.Hero.Sprite.Tint)
+			screen.DrawImage(lifeImage, op)
 		}
 	}
 }
 
-func (g *ArrowsAway) Update() error {
-	g.updateGamepads()
-
-	if len(g.gamepadIds) <= 0 {
-		g.state = NoClicker
+func (g *ArrowsAway) drawWaves(screen *ebiten.Image) {
+	level := g.world.Level()
+	if level.Waves() == 0 {
+		return
 	}
-
-	switch g.state {
-	case NoClicker:
-		if len(g.gamepadIds) > 0 {
-			g.state = NextStage
-		}
-	case NextStage:
-		if g.isPadButtonPressed() {
-			g.hero.Sprite.Center(g.width, g.height)
-			g.state = Running
-		}
-	case Running:
-		g.hero.Update(&g.gamepadIds, g.height, g.width)
-		g.updateHero()
-		g.updateArrows()
-		g.updateEnemies()
-		g.updateLevel()
-	case LostLife:
-		if g.isPadButtonPressed() {
-			g.hero.Sprite.Center(g.width, g.height)
-			for id, e := range g.enemies {
-				if !e.IsAlive() {
-					delete(g.enemies, id)
-				}
-				e.ToStart()
-			}
-			g.state = Running
-		}
-	case GameOver:
-		fallthrough
-	case Winner:
-		if g.isPadButtonPressed() {
-			g.enemies = make(map[string]*sprites.Enemy)
-			g.hero.Sprite.Center(g.width, g.height)
-			g.score = 0
-			g.lives = 3
-			g.state = NextStage
-			g.levelIndex = 0
-			g.levels[g.levelIndex].Reset()
-			g.levels[g.levelIndex].PopulateEnemies(g.width, g.height, g.enemies)
-		}
+	progress := fmt.Sprintf("Wave %d/%d", level.Wave(), level.Waves())
+	if ticks := level.NextWaveTicks(); ticks >= 0 {
+		seconds := (ticks + sprites.TicksPerSecond - 1) / sprites.TicksPerSecond
+		progress = fmt.Sprintf("%s - next in %ds", progress, seconds)
 	}
-	return nil
+	text.Draw(screen, progress, g.font, 10, 30, color.RGBA{0x00, 0x00, 0x00, 0xff})
 }
 
-func (g *ArrowsAway) tileFloor(screen *ebiten.Image) {
-	background := g.levels[g.levelIndex].GetBackground()
-	rows := (g.height / background.Bounds().Dy()) + 1
-	cols := (g.width / background.Bounds().Dx()) + 1
-
-	op := &ebiten.DrawImageOptions{}
-	for c := 0; c < cols; c++ {
-		for r := 0; r < rows; r++ {
-			op.GeoM.Reset()
-			op.GeoM.Translate(float64(c*background.Bounds().Dx()), float64(r*background.Bounds().Dy()))
-			screen.DrawImage(background, op)
-		}
+func (g *ArrowsAway) drawArena(screen *ebiten.Image) {
+	render.Floor(screen, g.world.Level().GetBackground(), g.width, g.height)
+	render.Map(screen, g.world.Level().Map())
+	for _, p := range g.world.Pickups() {
+		render.Pickup(screen, p)
 	}
-}
-
-func (g *ArrowsAway) drawCentered(screen *ebiten.Image, y int, t []string) {
-	for i, s := range t {
-		r := text.BoundString(g.font, s)
-		text.Draw(
-			screen,
-			s,
-			g.font,
-			(g.width / 2) - (r.Dx() / 2), y + (i * (r.Dy() + 10)), color.RGBA{0x00, 0x00, 0x00, 0xff})	
+	for _, p := range g.world.LivingPlayers() {
+		render.Hero(screen, p.Hero)
+	}
+	for _, a := range g.world.Arrows().All() {
+		render.Arrow(screen, a)
+	}
+	for _, a := range g.world.EnemyArrows().All() {
+		render.Arrow(screen, a)
 	}
+	for _, e := range g.world.Enemies().All() {
+		render.Enemy(screen, e)
+	}
+	if boss := g.world.Boss(); boss != nil && boss.IsAlive() {
+		g.drawCentered(screen, 30, []string{boss.Boss.Type.Title})
+		render.BossBar(screen, boss, g.width, 40)
+	}
+	g.drawWaves(screen)
+	g.drawScores(screen)
 }
 
 func (g *ArrowsAway) Draw(screen *ebiten.Image) {
-	switch g.state {
-	case NoClicker:
+	if g.diverged != nil {
+		defer g.drawCentered(screen, g.height-60, []string{"Replay diverged"})
+	}
+
+	if g.noClicker {
 		screen.Fill(color.RGBA{0x87, 0xCE, 0xEB, 0xff})
-		g.drawCentered(screen, 40, []string{"Plugin a clicker to get started.",})
-	case Running:
-		g.tileFloor(screen)
-		g.hero.Draw(screen)
-		for _, a := range g.arrows {
-			a.Draw(screen)
+		g.drawCentered(screen, 40, []string{"Plugin a clicker or keyboard to get started."})
+		return
+	}
+
+	players := g.world.Players()
+	switch g.world.State() {
+	case game.Running:
+		g.drawArena(screen)
+	case game.Paused:
+		g.drawArena(screen)
+		g.drawCentered(screen, 40, []string{"Paused. Press start or Escape to continue."})
+	case game.NextStage:
+		level := g.world.Level()
+		screen.Fill(color.RGBA{0x87, 0xCE, 0xEB, 0xff})
+		lines := []string{
+			level.GetName(),
+			fmt.Sprintf("%d - %d", g.world.LevelIndex()+1, level.GetStage()+1),
+			"Press a button or Enter to start",
 		}
-		for _, e := range g.enemies {
-			e.Draw(screen)
+		if boss := g.world.Boss(); boss != nil {
+			lines = []string{
+				level.GetName(),
+				"",
+				boss.Boss.Type.Title,
+				boss.Boss.Type.Subtitle,
+				"",
+				"Press a button or Enter to fight",
+			}
+			render.Portrait(screen, boss, g.width, g.height)
 		}
-		text.Draw(
-			screen,
-			fmt.Sprintf("Score: %d", g.score),
-			g.font,
-			10, g.height - 20, color.RGBA{0x00, 0x00, 0x00, 0xff})
-		lifeImage := images.GetImages().Life
-		op := &ebiten.DrawImageOptions{}
-		for i := 0 ; i < g.lives ; i++ {
-			op.GeoM.Reset()
-			op.GeoM.Translate(float64(g.width - 10 - (lifeImage.Bounds().Dx() * (i + 1))), float64(g.height - 20 - lifeImage.Bounds().Dy()))
-			screen.DrawImage(lifeImage, op)
+		if g.world.AtRunStart() {
+			lines = append(lines, "", "Move left or right to pick a weapon")
+			for _, p := range players {
+				lines = append(lines, fmt.Sprintf("P%d: < %s >", p.Id+1, p.Hero.Weapon.Name))
+			}
 		}
-	case NextStage:
-		level := g.levels[g.levelIndex]
-		screen.Fill(color.RGBA{0x87, 0xCE, 0xEB, 0xff})
-		g.drawCentered(screen, 40, []string{
-			level.GetName(),
-			fmt.Sprintf("%d - %d", g.levelIndex + 1, level.GetStage() + 1),
-			"Press a button to start",
-		})
-	case LostLife:
+		g.drawCentered(screen, 40, lines)
+	case game.LostLife:
 		screen.Fill(color.RGBA{0x87, 0xCE, 0xEB, 0xff})
-		g.drawCentered(screen, 40, []string{fmt.Sprintf("%d lives left. Press a button to keep trying.", g.lives),})
-	case Winner:
+		lines := []string{"Press a button or Enter to keep trying."}
+		for _, p := range players {
+			lines = append(lines, fmt.Sprintf("P%d: %d lives left", p.Id+1, p.Lives))
+		}
+		g.drawCentered(screen, 40, lines)
+	case game.Winner:
 		screen.Fill(color.RGBA{0x87, 0xCE, 0xEB, 0xff})
-		g.drawCentered(screen, 40, []string{"You won! Press a button to play again",})
-		g.hero.Winner(screen, g.width, g.height)
-	case GameOver:
+		g.drawCentered(screen, 40, append([]string{"You won! Press a button or Enter to play again"}, g.scoreLines()...))
+		if len(players) > 0 {
+			render.Pose(screen, players[0].Hero, sprites.HeroWinner, g.width, g.height)
+		}
+	case game.GameOver:
 		screen.Fill(color.RGBA{0x87, 0xCE, 0xEB, 0xff})
-		g.drawCentered(screen, 40, []string{"Game over. Press a button to try again",})
-		g.hero.GameOver(screen, g.width, g.height)
+		g.drawCentered(screen, 40, append([]string{"Game over. Press a button or Enter to try again"}, g.scoreLines()...))
+		if len(players) > 0 {
+			render.Pose(screen, players[0].Hero, sprites.HeroGameOver, g.width, g.height)
+		}
 	}
 }
 
 func (g *ArrowsAway) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	if g.playback != nil {
+		// a replay is shown at the size it was recorded at
+		g.width = g.world.Width()
+		g.height = g.world.Height()
+		return g.width, g.height
+	}
 	if (outsideHeight != g.height) || (outsideWidth != g.width) {
 		g.height = outsideHeight
 		g.width = outsideWidth
+		g.world.Resize(g.width, g.height)
+		g.event(replay.Event{Kind: replay.Resize, Width: g.width, Height: g.height})
 	}
 	return outsideWidth, outsideHeight
 }
 
-func (g *ArrowsAway) initialize() {
+// levels returns a fresh copy of the levels being played: the built in
+// levels along with those in levelsDir when one was given.
+func (g *ArrowsAway) levels() []*level.Level {
+	levels, err := level.LoadWithDefaults(g.levelsDir, g.enemyTypes, g.bossTypes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return levels
+}
+
+func levelNames(levels []*level.Level) []string {
+	names := []string{}
+	for _, l := range levels {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+func (g *ArrowsAway) initialize(seed int64, enemyTypes sprites.EnemyTypes, levelsDir string) {
 	tt, err := opentype.Parse(fonts.PressStart2PRegular_ttf)
 	if err != nil {
 		log.Fatal(err)
@@ -320,32 +302,118 @@ func (g *ArrowsAway) initialize() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	if g.gamepadIds == nil {
-		g.gamepadIds = map[ebiten.GamepadID]bool{}
+	if g.gamepads == nil {
+		g.gamepads = map[ebiten.GamepadID]int{}
 	}
+	g.sources = map[int]input.Source{}
+	if device.KeyboardAvailable() {
+		g.keyboard = device.NewKeyboardMouse()
+	}
+	g.keyboardPlayer = noPlayer
+	g.enemyTypes = enemyTypes
+	g.bossTypes = sprites.DefaultBossTypes(enemyTypes)
+	g.levelsDir = levelsDir
 	g.height = 1000
 	g.width = 1000
-	g.lastShotMilli = 0
-	g.levelIndex = 0
-	g.levels = append(g.levels, level.NewLevel("Goblins in the grass", images.GetImages().Goblin, images.GetImages().Grass, 40))
-	g.levels = append(g.levels, level.NewLevel("Skeletons on the stone", images.GetImages().Skeleton, images.GetImages().Stone, 40))
-	g.lives = 3
-	g.score = 0
-	g.hero = sprites.NewHero(images.GetImages().Hero)
-	g.hero.Sprite.Center(g.width, g.height)
-	g.arrows = make(map[string]*sprites.Arrow)
-	g.enemies = make(map[string]*sprites.Enemy)
-	g.levels[g.levelIndex].PopulateEnemies(g.width, g.height, g.enemies)
+	fixedSeed := seed != 0
+	if !fixedSeed {
+		seed = time.Now().UnixNano()
+	}
+	g.world = game.NewWorld(g.width, g.height, seed, fixedSeed, g.levels())
+}
+
+// startRecording writes every update from now on to path.
+func (g *ArrowsAway) startRecording(path string) (io.Closer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	g.recorder, err = replay.NewRecorder(f, replay.Header{
+		Seed:      g.world.Seed(),
+		FixedSeed: g.world.FixedSeed(),
+		Width:     g.world.Width(),
+		Height:    g.world.Height(),
+		Levels:    levelNames(g.world.Levels()),
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// startReplay replaces the world with the one the replay at path was
+// recorded in. Updates then come from the replay instead of the controllers.
+func (g *ArrowsAway) startReplay(path string) (io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	g.playback, err = replay.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	h := g.playback.Header()
+	levels := g.levels()
+	if fmt.Sprint(h.Levels) != fmt.Sprint(levelNames(levels)) {
+		f.Close()
+		return nil, fmt.Errorf("replay was recorded with levels %v", h.Levels)
+	}
+	g.width = h.Width
+	g.height = h.Height
+	g.world = game.NewWorld(h.Width, h.Height, h.Seed, h.FixedSeed, levels)
+	return f, nil
 }
 
 func main() {
+	seed := flag.Int64("seed", 0, "seed for the game's random numbers, picked at random when 0")
+	record := flag.String("record", "", "record the game to this replay file")
+	replayFile := flag.String("replay", "", "play back this replay file instead of reading the controllers")
+	enemyTypesFile := flag.String("enemy-types", "", "JSON file of extra enemy types, replacing built in types of the same name")
+	levelsDir := flag.String("levels", "", "directory of JSON level files to play after the built in levels, replacing any of the same name")
+	flag.Parse()
+
+	enemyTypes := sprites.DefaultEnemyTypes()
+	if *enemyTypesFile != "" {
+		if err := enemyTypes.LoadFile(*enemyTypesFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	game := &ArrowsAway{}
-	game.initialize()
+	game.initialize(*seed, enemyTypes, *levelsDir)
+
+	var file io.Closer
+	var err error
+	if *replayFile != "" {
+		file, err = game.startReplay(*replayFile)
+	} else if *record != "" {
+		file, err = game.startRecording(*record)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ebiten.SetWindowSize(game.width, game.height)
 	ebiten.SetWindowTitle("Arrows Away")
 	ebiten.SetWindowResizable(true)
 	ebiten.SetScreenTransparent(true)
-	if err := ebiten.RunGame(game); err != nil {
+	err = ebiten.RunGame(game)
+	if game.recorder != nil {
+		if cerr := game.recorder.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if file != nil {
+		file.Close()
+	}
+	if err == errReplayDone {
+		if game.diverged != nil {
+			os.Exit(1)
+		}
+		log.Printf("replay finished after %d frames", game.frames)
+	} else if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,124 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+const (
+	DefaultCellSize float64 = 64
+)
+
+type cell struct {
+	x, y int
+}
+
+// Grid is a uniform grid broadphase. Entries are identified by small
+// non-negative integers, normally their index in a slice owned by the
+// caller, and are stored in every cell their bounds overlap. The grid is
+// meant to be cleared and refilled every tick; cells keep their storage
+// between ticks so a warmed up grid does not allocate.
+type Grid struct {
+	cellSize float64
+	cells    map[cell][]int
+	bounds   []geom.Rect
+	seen     []uint32
+	stamp    uint32
+}
+
+func NewGrid(cellSize float64) *Grid {
+	return &Grid{
+		cellSize: cellSize,
+		cells:    make(map[cell][]int),
+	}
+}
+
+func (g *Grid) Clear() {
+	for c, ids := range g.cells {
+		g.cells[c] = ids[:0]
+	}
+	g.bounds = g.bounds[:0]
+}
+
+func (g *Grid) cellRange(r geom.Rect) (int, int, int, int) {
+	return int(math.Floor(r.Min.X / g.cellSize)),
+		int(math.Floor(r.Min.Y / g.cellSize)),
+		int(math.Floor(r.Max.X / g.cellSize)),
+		int(math.Floor(r.Max.Y / g.cellSize))
+}
+
+func (g *Grid) Insert(id int, bounds geom.Rect) {
+	for len(g.bounds) <= id {
+		g.bounds = append(g.bounds, geom.Rect{})
+	}
+	g.bounds[id] = bounds
+	minX, minY, maxX, maxY := g.cellRange(bounds)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			c := cell{x: x, y: y}
+			g.cells[c] = append(g.cells[c], id)
+		}
+	}
+}
+
+func (g *Grid) nextStamp() {
+	for len(g.seen) < len(g.bounds) {
+		g.seen = append(g.seen, 0)
+	}
+	g.stamp = g.stamp + 1
+	if g.stamp == 0 {
+		for i := range g.seen {
+			g.seen[i] = 0
+		}
+		g.stamp = 1
+	}
+}
+
+// Query appends to ids every entry whose bounds overlap r, each one once, in
+// the order they were inserted, and returns the extended slice.
+func (g *Grid) Query(r geom.Rect, ids []int) []int {
+	g.nextStamp()
+	start := len(ids)
+	minX, minY, maxX, maxY := g.cellRange(r)
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for _, id := range g.cells[cell{x: x, y: y}] {
+				if g.seen[id] == g.stamp {
+					continue
+				}
+				g.seen[id] = g.stamp
+				if g.bounds[id].Overlaps(r) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	sortInts(ids[start:])
+	return ids
+}
+
+// QuerySegment appends every entry whose bounds might touch the segment
+// from..to.
+func (g *Grid) QuerySegment(from, to geom.Vector, ids []int) []int {
+	return g.Query(geom.RectAround(from, to), ids)
+}
+
+// QueryRadius appends every entry whose bounds might be within radius of
+// center.
+func (g *Grid) QueryRadius(center geom.Vector, radius float64, ids []int) []int {
+	return g.Query(geom.Rect{
+		Min: geom.Vector{X: center.X - radius, Y: center.Y - radius},
+		Max: geom.Vector{X: center.X + radius, Y: center.Y + radius},
+	}, ids)
+}
+
+// sortInts is an insertion sort; query results are short and sort.Ints
+// would allocate.
+func sortInts(ids []int) {
+	for i := 1; i < len(ids); i++ {
+		for j := i; j > 0 && ids[j] < ids[j-1]; j-- {
+			ids[j], ids[j-1] = ids[j-1], ids[j]
+		}
+	}
+}
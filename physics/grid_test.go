@@ -0,0 +1,125 @@
+package physics
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+const (
+	entitySize float64 = 32
+	arenaSize  float64 = 2000
+)
+
+// randomBounds returns n entity sized boxes scattered over the arena, some
+// of them hanging off its edges.
+func randomBounds(n int, rng *rand.Rand) []geom.Rect {
+	bounds := make([]geom.Rect, n)
+	for i := range bounds {
+		min := geom.Vector{
+			X: (rng.Float64() * (arenaSize + entitySize)) - entitySize,
+			Y: (rng.Float64() * (arenaSize + entitySize)) - entitySize,
+		}
+		bounds[i] = geom.Rect{Min: min, Max: min.Add(geom.Vector{X: entitySize, Y: entitySize})}
+	}
+	return bounds
+}
+
+func fill(g *Grid, bounds []geom.Rect) {
+	g.Clear()
+	for i, b := range bounds {
+		g.Insert(i, b)
+	}
+}
+
+// scan is the brute force query the grid stands in for.
+func scan(bounds []geom.Rect, r geom.Rect, ids []int) []int {
+	for i, b := range bounds {
+		if b.Overlaps(r) {
+			ids = append(ids, i)
+		}
+	}
+	return ids
+}
+
+func TestGridMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	bounds := randomBounds(500, rng)
+	g := NewGrid(DefaultCellSize)
+	fill(g, bounds)
+
+	for i := 0; i < 200; i++ {
+		from := geom.Vector{X: rng.Float64() * arenaSize, Y: rng.Float64() * arenaSize}
+		to := from.Add(geom.Vector{X: (rng.Float64() - 0.5) * 300, Y: (rng.Float64() - 0.5) * 300})
+		radius := rng.Float64() * 100
+		around := geom.Rect{
+			Min: geom.Vector{X: from.X - radius, Y: from.Y - radius},
+			Max: geom.Vector{X: from.X + radius, Y: from.Y + radius},
+		}
+
+		if got, want := g.Query(bounds[i], nil), scan(bounds, bounds[i], nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("Query(%v) = %v, want %v", bounds[i], got, want)
+		}
+		if got, want := g.QuerySegment(from, to, nil), scan(bounds, geom.RectAround(from, to), nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("QuerySegment(%v, %v) = %v, want %v", from, to, got, want)
+		}
+		if got, want := g.QueryRadius(from, radius, nil), scan(bounds, around, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("QueryRadius(%v, %v) = %v, want %v", from, radius, got, want)
+		}
+	}
+}
+
+func TestGridQueryAppends(t *testing.T) {
+	g := NewGrid(DefaultCellSize)
+	fill(g, []geom.Rect{
+		{Min: geom.Vector{X: 0, Y: 0}, Max: geom.Vector{X: 100, Y: 100}},
+		{Min: geom.Vector{X: 500, Y: 500}, Max: geom.Vector{X: 510, Y: 510}},
+		{Min: geom.Vector{X: 50, Y: 50}, Max: geom.Vector{X: 60, Y: 60}},
+	})
+	got := g.Query(geom.Rect{Min: geom.Vector{X: 40, Y: 40}, Max: geom.Vector{X: 70, Y: 70}}, []int{7})
+	if want := []int{7, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Query = %v, want %v", got, want)
+	}
+}
+
+var sizes = []int{1000, 2000}
+
+// BenchmarkGridQuery rebuilds the grid and looks for everything touching
+// each entity, the work the world does for enemy collisions every tick.
+func BenchmarkGridQuery(b *testing.B) {
+	for _, n := range sizes {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			bounds := randomBounds(n, rand.New(rand.NewSource(1)))
+			g := NewGrid(DefaultCellSize)
+			ids := []int{}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				fill(g, bounds)
+				for _, r := range bounds {
+					ids = g.Query(r, ids[:0])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPairwise does the same work by checking every pair.
+func BenchmarkPairwise(b *testing.B) {
+	for _, n := range sizes {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			bounds := randomBounds(n, rand.New(rand.NewSource(1)))
+			ids := []int{}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, r := range bounds {
+					ids = scan(bounds, r, ids[:0])
+				}
+			}
+		})
+	}
+}
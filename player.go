@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/input/device"
+	"github.com/markrzasa/arrowsaway/replay"
+)
+
+const (
+	noPlayer int = -1
+)
+
+func (g *ArrowsAway) addPlayer(source input.Source) int {
+	id := g.world.AddPlayer().Id
+	g.sources[id] = source
+	g.event(replay.Event{Kind: replay.Join, Player: id})
+	return id
+}
+
+func (g *ArrowsAway) removePlayer(id int) {
+	g.world.RemovePlayer(id)
+	g.event(replay.Event{Kind: replay.Leave, Player: id})
+	delete(g.sources, id)
+	if g.keyboardPlayer == id {
+		g.keyboardPlayer = noPlayer
+	}
+}
+
+func (g *ArrowsAway) updateGamepads() {
+	g.gamepadIdsBuffer = inpututil.AppendJustConnectedGamepadIDs(g.gamepadIdsBuffer[:0])
+	for _, id := range g.gamepadIdsBuffer {
+		g.gamepads[id] = g.addPlayer(device.NewGamepad(id))
+	}
+	for id, p := range g.gamepads {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			g.removePlayer(p)
+			delete(g.gamepads, id)
+		}
+	}
+}
+
+// updatePlayers reads this frame's intent for every player and lets the
+// keyboard and mouse join as a player once they are used.
+func (g *ArrowsAway) updatePlayers() map[int]input.Intent {
+	g.updateGamepads()
+
+	intents := map[int]input.Intent{}
+	for _, p := range g.world.Players() {
+		intents[p.Id] = g.sources[p.Id].Intent(p.Hero.Position())
+	}
+	if g.keyboard != nil && g.keyboardPlayer == noPlayer {
+		intent := g.keyboard.Intent(geom.Vector{})
+		if !intent.IsIdle() {
+			g.keyboardPlayer = g.addPlayer(g.keyboard)
+			intents[g.keyboardPlayer] = intent
+		}
+	}
+	return intents
+}
+
+func (g *ArrowsAway) hasInput() bool {
+	return g.keyboard != nil || len(g.gamepads) > 0
+}
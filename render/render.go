@@ -0,0 +1,166 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/sprites"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+const (
+	healthMargin  int     = 2
+	bossBarMargin int     = 40
+	bossBarHeight float64 = 2
+)
+
+var textures = map[string]*ebiten.Image{}
+
+// Texture returns the named image uploaded for drawing.
+func Texture(name string) *ebiten.Image {
+	t, ok := textures[name]
+	if !ok {
+		t = ebiten.NewImageFromImage(images.Decode(name))
+		textures[name] = t
+	}
+	return t
+}
+
+func Tint(op *ebiten.DrawImageOptions, tint color.Color) {
+	if tint != nil {
+		r, g, b, a := tint.RGBA()
+		op.ColorM.Scale(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff, float64(a)/0xffff)
+	}
+}
+
+func Sprite(screen *ebiten.Image, s *sprites.Sprite) {
+	texture := Texture(s.Image)
+	op := &ebiten.DrawImageOptions{}
+	Tint(op, s.Tint)
+	op.GeoM.Translate(-float64(s.FrameWidth())/2, -float64(s.FrameHeight())/2)
+	op.GeoM.Rotate(s.Radians)
+	op.GeoM.Scale(s.ScaleX, s.ScaleY)
+	op.GeoM.Translate(float64(s.X), float64(s.Y))
+	subImageRect := image.Rect(s.Frame*s.FrameWidth(), 0, (s.Frame+1)*s.FrameWidth(), s.FrameHeight())
+	screen.DrawImage(texture.SubImage(subImageRect).(*ebiten.Image), op)
+}
+
+func Hero(screen *ebiten.Image, h *sprites.Hero) {
+	Sprite(screen, h.Sprite)
+}
+
+// Pose draws a large copy of the hero in the middle of the screen using one
+// of the hero's celebration frames.
+func Pose(screen *ebiten.Image, h *sprites.Hero, frame, width, height int) {
+	pose := *h.Sprite
+	pose.Scale(10)
+	pose.X = width / 2
+	pose.Y = height / 2
+	pose.Radians = 0
+	pose.Frame = frame
+	Sprite(screen, &pose)
+}
+
+func healthBar(screen *ebiten.Image, e *sprites.Enemy) {
+	texture := Texture(images.EnemyHealth)
+	barWidth := texture.Bounds().Dx()
+	barHeight := texture.Bounds().Dy()
+	scaledBounds := e.Sprite.ScaledBounds()
+	y := scaledBounds.Max.Y + healthMargin
+	if y > screen.Bounds().Dy() {
+		y = scaledBounds.Min.Y - healthMargin
+	}
+	subImageWidth := int(float64(barWidth) * e.Health())
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-float64(subImageWidth)/2, -float64(barHeight)/2)
+	op.GeoM.Scale(e.Sprite.ScaleX, 1)
+	op.GeoM.Translate(float64(e.Sprite.X), float64(y))
+	subImageRect := image.Rect(0, 0, subImageWidth, barHeight)
+	screen.DrawImage(texture.SubImage(subImageRect).(*ebiten.Image), op)
+}
+
+func Enemy(screen *ebiten.Image, e *sprites.Enemy) {
+	Sprite(screen, e.Sprite)
+	if e.IsAlive() && e.Boss == nil {
+		healthBar(screen, e)
+	}
+}
+
+// BossBar draws the boss's health across the top of the screen at y, over a
+// faded bar showing what it started with.
+func BossBar(screen *ebiten.Image, e *sprites.Enemy, width, y int) {
+	texture := Texture(images.EnemyHealth)
+	barWidth := float64(width - (2 * bossBarMargin))
+	scaleX := barWidth / float64(texture.Bounds().Dx())
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scaleX, bossBarHeight)
+	op.GeoM.Translate(float64(bossBarMargin), float64(y))
+	op.ColorM.Scale(1, 1, 1, 0.3)
+	screen.DrawImage(texture, op)
+
+	subImageWidth := int(float64(texture.Bounds().Dx()) * e.Health())
+	op.ColorM.Reset()
+	subImageRect := image.Rect(0, 0, subImageWidth, texture.Bounds().Dy())
+	screen.DrawImage(texture.SubImage(subImageRect).(*ebiten.Image), op)
+}
+
+// Portrait draws a large still of the boss in the middle of the screen for
+// its title card.
+func Portrait(screen *ebiten.Image, e *sprites.Enemy, width, height int) {
+	portrait := *e.Sprite
+	portrait.Scale(e.Boss.Type.Scale * 2)
+	portrait.X = width / 2
+	portrait.Y = height / 2
+	portrait.Radians = 0
+	portrait.Frame = 0
+	Sprite(screen, &portrait)
+}
+
+func Arrow(screen *ebiten.Image, a *sprites.Arrow) {
+	Sprite(screen, &a.Sprite)
+}
+
+func Pickup(screen *ebiten.Image, p *sprites.Pickup) {
+	Sprite(screen, p.Sprite)
+}
+
+// Floor tiles the background image across the whole screen.
+func Floor(screen *ebiten.Image, background string, width, height int) {
+	texture := Texture(background)
+	rows := (height / texture.Bounds().Dy()) + 1
+	cols := (width / texture.Bounds().Dx()) + 1
+
+	op := &ebiten.DrawImageOptions{}
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			op.GeoM.Reset()
+			op.GeoM.Translate(float64(c*texture.Bounds().Dx()), float64(r*texture.Bounds().Dy()))
+			screen.DrawImage(texture, op)
+		}
+	}
+}
+
+// Map draws the walls and rocks of the arena over the floor.
+func Map(screen *ebiten.Image, m *tilemap.Map) {
+	op := &ebiten.DrawImageOptions{}
+	for row := 0; row < m.Rows(); row++ {
+		for col := 0; col < m.Cols(); col++ {
+			var texture *ebiten.Image
+			switch m.At(col, row) {
+			case tilemap.Wall:
+				texture = Texture(images.Wall)
+			case tilemap.Rock:
+				texture = Texture(images.Rock)
+			default:
+				continue
+			}
+			r := m.TileRect(col, row)
+			op.GeoM.Reset()
+			op.GeoM.Scale(m.Size()/float64(texture.Bounds().Dx()), m.Size()/float64(texture.Bounds().Dy()))
+			op.GeoM.Translate(r.Min.X, r.Min.Y)
+			screen.DrawImage(texture, op)
+		}
+	}
+}
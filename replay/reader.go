@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+)
+
+var ErrNotReplay = errors.New("not a replay file")
+
+type Reader struct {
+	r      *bufio.Reader
+	buf    [8]byte
+	header Header
+	err    error
+}
+
+// NewReader reads the header of the replay in r. Frames are then read one
+// at a time with Next.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrNotReplay
+	}
+	rr := &Reader{r: bufio.NewReader(gz)}
+	m := make([]byte, len(magic))
+	rr.read(m)
+	if rr.err != nil || string(m) != magic {
+		return nil, ErrNotReplay
+	}
+	if v := rr.uvarint(); rr.err == nil && v != version {
+		return nil, fmt.Errorf("unsupported replay version %d", v)
+	}
+	h := &rr.header
+	h.Seed = rr.varint()
+	h.FixedSeed = rr.byte() != 0
+	h.Width = int(rr.uvarint())
+	h.Height = int(rr.uvarint())
+	h.ChecksumInterval = int64(rr.uvarint())
+	levels := rr.uvarint()
+	if rr.err == nil && levels > maxLevels {
+		rr.err = fmt.Errorf("%d levels", levels)
+	}
+	for i := uint64(0); i < levels && rr.err == nil; i++ {
+		n := rr.uvarint()
+		if rr.err == nil && n > maxLevelName {
+			rr.err = fmt.Errorf("level name of %d bytes", n)
+			break
+		}
+		l := make([]byte, n)
+		rr.read(l)
+		h.Levels = append(h.Levels, string(l))
+	}
+	if rr.err != nil {
+		return nil, rr.corrupt()
+	}
+	return rr, nil
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+func (r *Reader) corrupt() error {
+	if r.err == io.EOF {
+		r.err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("corrupt replay: %w", r.err)
+}
+
+func (r *Reader) read(b []byte) {
+	if r.err == nil {
+		_, r.err = io.ReadFull(r.r, b)
+	}
+}
+
+func (r *Reader) byte() byte {
+	r.read(r.buf[:1])
+	return r.buf[0]
+}
+
+func (r *Reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	r.err = err
+	return v
+}
+
+func (r *Reader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(r.r)
+	r.err = err
+	return v
+}
+
+func (r *Reader) uint64() uint64 {
+	r.read(r.buf[:8])
+	return binary.LittleEndian.Uint64(r.buf[:8])
+}
+
+func (r *Reader) vector() geom.Vector {
+	x := math.Float64frombits(r.uint64())
+	y := math.Float64frombits(r.uint64())
+	return geom.Vector{X: x, Y: y}
+}
+
+// Next returns the next recorded frame, or io.EOF once the recording ends.
+func (r *Reader) Next() (*Frame, error) {
+	f := &Frame{Intents: map[int]input.Intent{}}
+	first := true
+	for {
+		op := r.byte()
+		if r.err == io.EOF && first {
+			return nil, io.EOF
+		}
+		if r.err != nil {
+			return nil, r.corrupt()
+		}
+		first = false
+		switch op {
+		case opJoin:
+			f.Events = append(f.Events, Event{Kind: Join, Player: int(r.uvarint())})
+		case opLeave:
+			f.Events = append(f.Events, Event{Kind: Leave, Player: int(r.uvarint())})
+		case opResize:
+			w := int(r.uvarint())
+			h := int(r.uvarint())
+			f.Events = append(f.Events, Event{Kind: Resize, Width: w, Height: h})
+		case opPause:
+			f.Events = append(f.Events, Event{Kind: Pause})
+		case opIntent:
+			id := int(r.uvarint())
+			flags := r.byte()
+			i := input.Intent{
+				Fire:    flags&intentFire != 0,
+				Confirm: flags&intentConfirm != 0,
+				Pause:   flags&intentPause != 0,
+			}
+			if flags&intentMove != 0 {
+				i.Move = r.vector()
+			}
+			if flags&intentAim != 0 {
+				i.Aim = r.vector()
+			}
+			f.Intents[id] = i
+		case opChecksum:
+			f.Checksum = r.uint64()
+			f.HasChecksum = true
+		case opUpdate, opIdle:
+			f.Idle = op == opIdle
+			return f, nil
+		default:
+			if r.err == nil {
+				r.err = fmt.Errorf("unknown opcode %d", op)
+			}
+		}
+		if r.err != nil {
+			return nil, r.corrupt()
+		}
+	}
+}
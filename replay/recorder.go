@@ -0,0 +1,208 @@
+package replay
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/markrzasa/arrowsaway/game"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+)
+
+// A replay file is a gzip stream starting with magic, the format version and
+// the Header. Each frame follows as a list of opcodes ending in opUpdate or
+// opIdle. Intents only store the parts that are set, so the long stretches
+// where nothing changes compress well.
+const (
+	magic   = "ARROWSAWAY"
+	version = 1
+)
+
+// the most levels a header lists and the longest name one can have, so a
+// corrupt file cannot ask the reader for more memory than a replay needs
+const (
+	maxLevels    = 1024
+	maxLevelName = 1024
+)
+
+const (
+	opJoin byte = iota + 1
+	opLeave
+	opResize
+	opPause
+	opIntent
+	opChecksum
+	opUpdate
+	opIdle
+)
+
+const (
+	intentFire byte = 1 << iota
+	intentConfirm
+	intentPause
+	intentMove
+	intentAim
+)
+
+type Recorder struct {
+	gz       *gzip.Writer
+	buf      [binary.MaxVarintLen64]byte
+	interval int64
+	frame    int64
+	err      error
+}
+
+// NewRecorder writes h to w and returns a Recorder for the frames that
+// follow. Close must be called to flush the recording.
+func NewRecorder(w io.Writer, h Header) (*Recorder, error) {
+	if h.ChecksumInterval <= 0 {
+		h.ChecksumInterval = DefaultChecksumInterval
+	}
+	if len(h.Levels) > maxLevels {
+		return nil, fmt.Errorf("a replay can list at most %d levels", maxLevels)
+	}
+	for _, l := range h.Levels {
+		if len(l) > maxLevelName {
+			return nil, fmt.Errorf("level names can be at most %d bytes long", maxLevelName)
+		}
+	}
+	r := &Recorder{
+		gz:       gzip.NewWriter(w),
+		interval: h.ChecksumInterval,
+	}
+	r.write([]byte(magic))
+	r.uvarint(version)
+	r.varint(h.Seed)
+	r.bool(h.FixedSeed)
+	r.uvarint(uint64(h.Width))
+	r.uvarint(uint64(h.Height))
+	r.uvarint(uint64(h.ChecksumInterval))
+	r.uvarint(uint64(len(h.Levels)))
+	for _, l := range h.Levels {
+		r.uvarint(uint64(len(l)))
+		r.write([]byte(l))
+	}
+	return r, r.err
+}
+
+func (r *Recorder) write(b []byte) {
+	if r.err == nil {
+		_, r.err = r.gz.Write(b)
+	}
+}
+
+func (r *Recorder) byte(b byte) {
+	r.buf[0] = b
+	r.write(r.buf[:1])
+}
+
+func (r *Recorder) bool(b bool) {
+	if b {
+		r.byte(1)
+	} else {
+		r.byte(0)
+	}
+}
+
+func (r *Recorder) uvarint(v uint64) {
+	r.write(r.buf[:binary.PutUvarint(r.buf[:], v)])
+}
+
+func (r *Recorder) varint(v int64) {
+	r.write(r.buf[:binary.PutVarint(r.buf[:], v)])
+}
+
+func (r *Recorder) float(f float64) {
+	binary.LittleEndian.PutUint64(r.buf[:8], math.Float64bits(f))
+	r.write(r.buf[:8])
+}
+
+func (r *Recorder) vector(v geom.Vector) {
+	r.float(v.X)
+	r.float(v.Y)
+}
+
+func (r *Recorder) intent(id int, i input.Intent) {
+	flags := byte(0)
+	if i.Fire {
+		flags = flags | intentFire
+	}
+	if i.Confirm {
+		flags = flags | intentConfirm
+	}
+	if i.Pause {
+		flags = flags | intentPause
+	}
+	if !i.Move.IsZero() {
+		flags = flags | intentMove
+	}
+	if !i.Aim.IsZero() {
+		flags = flags | intentAim
+	}
+	if flags == 0 {
+		return
+	}
+	r.byte(opIntent)
+	r.uvarint(uint64(id))
+	r.byte(flags)
+	if flags&intentMove != 0 {
+		r.vector(i.Move)
+	}
+	if flags&intentAim != 0 {
+		r.vector(i.Aim)
+	}
+}
+
+// Record writes f, which must already have been applied to world. Every
+// ChecksumInterval frames the state of world is stored along with it.
+func (r *Recorder) Record(f *Frame, world *game.World) error {
+	for _, e := range f.Events {
+		switch e.Kind {
+		case Join:
+			r.byte(opJoin)
+			r.uvarint(uint64(e.Player))
+		case Leave:
+			r.byte(opLeave)
+			r.uvarint(uint64(e.Player))
+		case Resize:
+			r.byte(opResize)
+			r.uvarint(uint64(e.Width))
+			r.uvarint(uint64(e.Height))
+		case Pause:
+			r.byte(opPause)
+		}
+	}
+
+	ids := []int{}
+	for id := range f.Intents {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		r.intent(id, f.Intents[id])
+	}
+
+	r.frame = r.frame + 1
+	if r.frame%r.interval == 0 {
+		r.byte(opChecksum)
+		binary.LittleEndian.PutUint64(r.buf[:8], world.Checksum())
+		r.write(r.buf[:8])
+	}
+	if f.Idle {
+		r.byte(opIdle)
+	} else {
+		r.byte(opUpdate)
+	}
+	return r.err
+}
+
+func (r *Recorder) Close() error {
+	if err := r.gz.Close(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
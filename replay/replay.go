@@ -0,0 +1,99 @@
+// Package replay records everything that reaches the game World during a run
+// and plays it back, checking along the way that the World still ends up in
+// the same state.
+package replay
+
+import (
+	"fmt"
+
+	"github.com/markrzasa/arrowsaway/game"
+	"github.com/markrzasa/arrowsaway/input"
+)
+
+const (
+	DefaultChecksumInterval int64 = 60
+)
+
+// Header is everything needed to build the World a recording was made with.
+type Header struct {
+	Seed             int64
+	FixedSeed        bool
+	Width, Height    int
+	Levels           []string
+	ChecksumInterval int64
+}
+
+type EventKind int
+
+const (
+	Join EventKind = iota
+	Leave
+	Resize
+	Pause
+)
+
+// Event is a change made to the World between ticks, such as a player
+// plugging in a controller or the window changing size.
+type Event struct {
+	Kind          EventKind
+	Player        int
+	Width, Height int
+}
+
+// Frame is one call to the game's Update. Events are applied in order before
+// the World is advanced with Intents. Idle frames do not advance the World.
+type Frame struct {
+	Events      []Event
+	Intents     map[int]input.Intent
+	Idle        bool
+	Checksum    uint64
+	HasChecksum bool
+}
+
+// Divergence is reported when a replayed World no longer matches the
+// recording.
+type Divergence struct {
+	Frame int64
+	Tick  int64
+	Want  uint64
+	Got   uint64
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("replay diverged at frame %d (tick %d): checksum %016x, recorded %016x",
+		d.Frame, d.Tick, d.Got, d.Want)
+}
+
+// Apply makes the same changes to world that were made when f was recorded.
+func Apply(world *game.World, f *Frame) error {
+	for _, e := range f.Events {
+		switch e.Kind {
+		case Join:
+			if p := world.AddPlayer(); p.Id != e.Player {
+				return fmt.Errorf("replay joined player %d, recorded %d", p.Id, e.Player)
+			}
+		case Leave:
+			world.RemovePlayer(e.Player)
+		case Resize:
+			world.Resize(e.Width, e.Height)
+		case Pause:
+			world.Pause()
+		}
+	}
+	if !f.Idle {
+		world.Update(f.Intents)
+	}
+	return nil
+}
+
+// Check compares world with the checksum recorded for frame n, if there is
+// one.
+func Check(world *game.World, n int64, f *Frame) error {
+	if !f.HasChecksum {
+		return nil
+	}
+	if got := world.Checksum(); got != f.Checksum {
+		return &Divergence{Frame: n, Tick: world.Tick(), Want: f.Checksum, Got: got}
+	}
+	return nil
+}
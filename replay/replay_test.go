@@ -0,0 +1,224 @@
+package replay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/game"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/level"
+	"github.com/markrzasa/arrowsaway/sprites"
+)
+
+func newWorld(h Header) *game.World {
+	enemyTypes := sprites.DefaultEnemyTypes()
+	levels := level.DefaultLevels(enemyTypes, sprites.DefaultBossTypes(enemyTypes))
+	return game.NewWorld(h.Width, h.Height, h.Seed, h.FixedSeed, levels)
+}
+
+// record applies frames to a world made from h and returns the recording.
+func record(t *testing.T, h Header, frames []*Frame) ([]byte, []uint64) {
+	t.Helper()
+	world := newWorld(h)
+	out := &bytes.Buffer{}
+	r, err := NewRecorder(out, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums := []uint64{}
+	for _, f := range frames {
+		if err := Apply(world, f); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Record(f, world); err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, world.Checksum())
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes(), sums
+}
+
+func TestRoundTrip(t *testing.T) {
+	h := Header{
+		Seed:             42,
+		FixedSeed:        true,
+		Width:            800,
+		Height:           600,
+		Levels:           []string{"Goblins in the grass", "Skeletons on the stone"},
+		ChecksumInterval: 2,
+	}
+	frames := []*Frame{
+		{
+			Events:  []Event{{Kind: Join, Player: 0}, {Kind: Resize, Width: 640, Height: 480}},
+			Intents: map[int]input.Intent{0: {Confirm: true}},
+		},
+		{
+			Intents: map[int]input.Intent{0: {Move: geom.Vector{X: 0.5, Y: -0.25}, Aim: geom.Vector{X: -1, Y: 0.125}, Fire: true}},
+		},
+		{
+			Events:  []Event{{Kind: Join, Player: 1}, {Kind: Pause}},
+			Intents: map[int]input.Intent{},
+			Idle:    true,
+		},
+		{
+			Events:  []Event{{Kind: Leave, Player: 0}},
+			Intents: map[int]input.Intent{1: {Pause: true, Move: geom.Vector{X: 1}}},
+		},
+	}
+	data, sums := record(t, h, frames)
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Header(), h) {
+		t.Errorf("header %+v, want %+v", r.Header(), h)
+	}
+	for i, want := range frames {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got.Events, want.Events) || !reflect.DeepEqual(got.Intents, want.Intents) || got.Idle != want.Idle {
+			t.Errorf("frame %d is %+v, want %+v", i, got, want)
+		}
+		if hasChecksum := (i+1)%2 == 0; got.HasChecksum != hasChecksum {
+			t.Errorf("frame %d has checksum %v, want %v", i, got.HasChecksum, hasChecksum)
+		} else if hasChecksum && got.Checksum != sums[i] {
+			t.Errorf("frame %d checksum %016x, want %016x", i, got.Checksum, sums[i])
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("after the last frame got %v, want io.EOF", err)
+	}
+}
+
+// TestPlaybackMatches records a scripted game long enough to fight, score and lose
+// lives, and checks that playing it back ends up in the same state.
+func TestPlaybackMatches(t *testing.T) {
+	h := Header{Seed: 7, Width: 1000, Height: 1000, ChecksumInterval: 10}
+	frames := []*Frame{{Events: []Event{{Kind: Join, Player: 0}}, Intents: map[int]input.Intent{0: {Confirm: true}}}}
+	for i := 0; i < 1200; i++ {
+		aim := geom.FromAngle(float64(i) / 20)
+		move := geom.FromAngle(float64(i) / 50).Scale(0.5)
+		frames = append(frames, &Frame{Intents: map[int]input.Intent{0: {Move: move, Aim: aim, Fire: true, Confirm: i%100 == 0}}})
+	}
+	data, _ := record(t, h, frames)
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := newWorld(r.Header())
+	for n := int64(0); ; n++ {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Apply(world, f); err != nil {
+			t.Fatal(err)
+		}
+		if err := Check(world, n, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func gzipped(b []byte) []byte {
+	out := &bytes.Buffer{}
+	gz := gzip.NewWriter(out)
+	gz.Write(b)
+	gz.Close()
+	return out.Bytes()
+}
+
+func TestBadInput(t *testing.T) {
+	header := append([]byte(magic), version, 0, 0, 1, 1, 1, 0)
+	// levels returns the header with its list of levels, which is empty in
+	// header, replaced by b
+	levels := func(b ...byte) []byte {
+		return append(append([]byte{}, header[:len(header)-1]...), b...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not gzip", []byte("ARROWSAWAY"), ErrNotReplay.Error()},
+		{"wrong magic", gzipped([]byte("ARROWSAHEAD")), ErrNotReplay.Error()},
+		{"newer version", gzipped(append([]byte(magic), version+1)), "unsupported replay version"},
+		{"short header", gzipped(header[:len(header)-2]), "corrupt replay"},
+		{"too many levels", gzipped(levels(0xff, 0xff, 0xff, 0xff, 0x0f)), "corrupt replay: 4294967295 levels"},
+		{"huge level name", gzipped(levels(1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)), "corrupt replay: level name of 9223372036854775807 bytes"},
+		{"short level name", gzipped(levels(1, 10, 'a')), "corrupt replay"},
+		{"unknown opcode", gzipped(append(header, 99)), "unknown opcode"},
+		{"unfinished frame", gzipped(append(header, opIntent, 0, intentMove, 1, 2)), "corrupt replay"},
+	}
+	for _, tt := range tests {
+		err := readAll(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRecordLevelLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []string
+		want   string
+	}{
+		{"too many levels", make([]string, maxLevels+1), "at most 1024 levels"},
+		{"long level name", []string{strings.Repeat("a", maxLevelName+1)}, "at most 1024 bytes long"},
+	}
+	for _, tt := range tests {
+		_, err := NewRecorder(io.Discard, Header{Levels: tt.levels})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	h := Header{Seed: 1, Width: 1000, Height: 1000}
+	frames := []*Frame{{Events: []Event{{Kind: Join, Player: 0}}, Intents: map[int]input.Intent{0: {Confirm: true}}}}
+	for i := 0; i < 100; i++ {
+		frames = append(frames, &Frame{Intents: map[int]input.Intent{0: {Aim: geom.Vector{X: 1}, Fire: true}}})
+	}
+	data, _ := record(t, h, frames)
+	if err := readAll(data); err != nil {
+		t.Fatalf("whole replay: %v", err)
+	}
+	for n := 0; n < len(data); n++ {
+		if err := readAll(data[:n]); err == nil {
+			t.Errorf("replay cut to %d of %d bytes read without an error", n, len(data))
+		}
+	}
+}
+
+// readAll reads every frame of a replay and returns the first error other
+// than the io.EOF that ends it.
+func readAll(data []byte) error {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for {
+		if _, err := r.Next(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
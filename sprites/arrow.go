@@ -1,22 +1,60 @@
 package sprites
 
 import (
+	"image/color"
 	"math"
 
-	"github.com/google/uuid"
-	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/markrzasa/arrowsaway/entity"
+	"github.com/markrzasa/arrowsaway/geom"
 	"github.com/markrzasa/arrowsaway/images"
 )
 
 const (
-	animations int = 50
+	DefaultArrowSpeed float64 = 10
+	DefaultArrowRange float64 = 900
+
+	// NoOwner is the owner of arrows shot by enemies
+	NoOwner int = -1
 )
 
+var enemyArrowTint = color.RGBA{0xff, 0x50, 0x50, 0xff}
+
+type ArrowKind int
+
+const (
+	PlainArrow ArrowKind = iota
+	SpreadArrow
+	PiercingArrow
+	ExplosiveArrow
+)
+
+func arrowImage(kind ArrowKind) string {
+	switch kind {
+	case SpreadArrow:
+		return images.SpreadArrow
+	case PiercingArrow:
+		return images.PiercingArrow
+	case ExplosiveArrow:
+		return images.ExplosiveArrow
+	default:
+		return images.Arrow
+	}
+}
+
 type Arrow struct {
-	Id         string
-	m, b       float64
-	EndX, EndY int
-	xInc, yInc  int
+	Owner       int
+	Kind        ArrowKind
+	Damage      int
+	Pierce      int
+	BlastRadius float64
+	struck      []entity.ID
+	Previous    geom.Vector
+	Position    geom.Vector
+	Direction   geom.Vector
+	Speed       float64
+	Range       float64
+	travelled   float64
+	stopped     bool
 	Sprite      Sprite
 }
 
@@ -32,49 +70,77 @@ func (a *Arrow) IsOffScreen(width, height int) bool {
 	return false
 }
 
-func (a *Arrow) Update() {
-	if a.xInc != 0 {
-		a.Sprite.X = a.Sprite.X + a.xInc
-		a.Sprite.Y = int((a.m * float64(a.Sprite.X)) + a.b)
-	} else {
-		a.Sprite.Y = a.Sprite.Y + a.yInc
-		a.Sprite.X = int((float64(a.Sprite.Y) - a.b) / a.m)
+func (a *Arrow) HasStruck(enemy entity.ID) bool {
+	for _, s := range a.struck {
+		if s == enemy {
+			return true
+		}
 	}
+	return false
 }
 
-func (a *Arrow) Draw(screen *ebiten.Image) {
-	a.Sprite.Draw(screen, 0)
+// Strike records that the arrow hit enemy and reports whether it is used up.
+// Piercing arrows keep flying until they have passed through Pierce enemies.
+func (a *Arrow) Strike(enemy entity.ID) bool {
+	a.struck = append(a.struck, enemy)
+	return len(a.struck) > a.Pierce
 }
 
-func NewArrow(startX, startY, endX, endY int) *Arrow {
-	deltaY := endY - startY
-	deltaX := endX - startX
-	m := float64(deltaY) / float64(deltaX)
-	xIncrement := 0
-	if startX < endX {
-		xIncrement = (endX - startX) / animations
-	} else {
-		xIncrement = -1 * ((startX - endX) / animations)
-	}
-	yIncrement := 0
-	if startY < endY {
-		yIncrement = (endY - startY) / animations
-	} else {
-		yIncrement = -1 * ((startY - endY) / animations)
-	}
-	arrowImage := images.GetImages().Arrow
-	arrow := &Arrow{
-		Id:      uuid.New().String(),
-		m:       m,
-		b:       float64(startY) - float64(m * float64(startX)),
-		EndX:    endX,
-		EndY:    endY,
-		xInc:    xIncrement,
-		yInc:    yIncrement,
-		Sprite:  *NewSprite(arrowImage.Bounds().Dx(), arrowImage),
-	}
-	arrow.Sprite.X = startX
-	arrow.Sprite.Y = startY
-	arrow.Sprite.Radians = math.Atan2(float64(deltaY), float64(deltaX)) - (45 * math.Pi/180)
+// PointAt returns the point a fraction t of the way along the path the arrow
+// took during its last update.
+func (a *Arrow) PointAt(t float64) geom.Vector {
+	return a.Previous.Add(a.Position.Sub(a.Previous).Scale(t))
+}
+
+// IsSpent reports whether the arrow has flown its full range or been
+// stopped.
+func (a *Arrow) IsSpent() bool {
+	return a.stopped || a.travelled >= a.Range
+}
+
+// Stop cuts the arrow's last move short a fraction t of the way along, where
+// it hit something solid.
+func (a *Arrow) Stop(t float64) {
+	a.Position = a.PointAt(t)
+	a.Sprite.X = int(math.Round(a.Position.X))
+	a.Sprite.Y = int(math.Round(a.Position.Y))
+	a.stopped = true
+}
+
+func (a *Arrow) Update() {
+	a.Previous = a.Position
+	a.Position = a.Position.Add(a.Direction.Scale(a.Speed))
+	a.travelled = a.travelled + a.Speed
+	a.Sprite.X = int(math.Round(a.Position.X))
+	a.Sprite.Y = int(math.Round(a.Position.Y))
+}
+
+// NewArrow creates an arrow at start flying toward direction. The direction
+// is normalized so every arrow moves speed pixels per update no matter how
+// far the player aimed.
+func NewArrow(owner int, kind ArrowKind, start, direction geom.Vector, speed, maxRange float64) *Arrow {
+	arrow := &Arrow{}
+	arrow.reset(owner, kind, start, direction, speed, maxRange)
 	return arrow
 }
+
+func (a *Arrow) reset(owner int, kind ArrowKind, start, direction geom.Vector, speed, maxRange float64) {
+	arrowImage := arrowImage(kind)
+	arrowWidth, _ := images.Size(arrowImage)
+	*a = Arrow{
+		Owner:     owner,
+		Kind:      kind,
+		Damage:    1,
+		struck:    a.struck[:0],
+		Previous:  start,
+		Position:  start,
+		Direction: direction.Normalize(),
+		Speed:     speed,
+		Range:     maxRange,
+		travelled: 0,
+	}
+	a.Sprite.reset(arrowWidth, arrowImage)
+	a.Sprite.X = int(math.Round(start.X))
+	a.Sprite.Y = int(math.Round(start.Y))
+	a.Sprite.Radians = a.Direction.Angle() - (45 * math.Pi / 180)
+}
@@ -0,0 +1,109 @@
+package sprites
+
+import (
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// EnemyBehavior decides how an enemy moves. Velocity returns how far the
+// enemy wants to move this step given where the hero it is after is. Enemies
+// keep whatever state a behavior needs, so one behavior value is shared by
+// every enemy of a type.
+type EnemyBehavior interface {
+	Velocity(e *Enemy, target geom.Vector) geom.Vector
+}
+
+var behaviors = map[string]EnemyBehavior{
+	"chaser":   Chaser{},
+	"flanker":  Flanker{Angle: math.Pi / 3, Range: 300},
+	"zigzag":   ZigZag{Angle: math.Pi / 4, Ticks: TicksPerSecond / 2},
+	"ambusher": Ambusher{WaitTicks: TicksPerSecond * 3 / 2, DashTicks: TicksPerSecond / 2, DashSpeed: 4},
+	"coward":   Coward{Near: 200, Far: 300},
+}
+
+// Behavior returns the built in behavior called name.
+func Behavior(name string) (EnemyBehavior, bool) {
+	b, ok := behaviors[name]
+	return b, ok
+}
+
+func toward(e *Enemy, target geom.Vector) (geom.Vector, float64) {
+	delta := target.Sub(e.position)
+	return delta.Normalize(), delta.Length()
+}
+
+// Chaser walks straight at the hero.
+type Chaser struct{}
+
+func (Chaser) Velocity(e *Enemy, target geom.Vector) geom.Vector {
+	direction, distance := toward(e, target)
+	return direction.Scale(math.Min(e.speed(), distance))
+}
+
+// Flanker swings out to one side and curls in, approaching the hero from
+// the flank. It heads Angle away from the hero while further than Range and
+// turns straight at the hero once within it.
+type Flanker struct {
+	Angle float64
+	Range float64
+}
+
+func (f Flanker) Velocity(e *Enemy, target geom.Vector) geom.Vector {
+	direction, distance := toward(e, target)
+	if distance > f.Range {
+		direction = direction.Rotate(f.Angle * e.side)
+	}
+	return direction.Scale(math.Min(e.speed(), distance))
+}
+
+// ZigZag heads for the hero Angle to one side and then the other, switching
+// every Ticks.
+type ZigZag struct {
+	Angle float64
+	Ticks int64
+}
+
+func (z ZigZag) Velocity(e *Enemy, target geom.Vector) geom.Vector {
+	direction, distance := toward(e, target)
+	angle := z.Angle * e.side
+	if (e.stateTicks/z.Ticks)%2 == 1 {
+		angle = -angle
+	}
+	return direction.Rotate(angle).Scale(math.Min(e.speed(), distance))
+}
+
+// Ambusher stands still for WaitTicks and then dashes at the hero at
+// DashSpeed times its normal speed for DashTicks, over and over.
+type Ambusher struct {
+	WaitTicks int64
+	DashTicks int64
+	DashSpeed float64
+}
+
+func (a Ambusher) Velocity(e *Enemy, target geom.Vector) geom.Vector {
+	if e.stateTicks%(a.WaitTicks+a.DashTicks) < a.WaitTicks {
+		return geom.Vector{}
+	}
+	direction, distance := toward(e, target)
+	return direction.Scale(math.Min(e.speed()*a.DashSpeed, distance))
+}
+
+// Coward keeps between Near and Far from the hero, backing off when the hero
+// comes closer and circling while at a comfortable distance.
+type Coward struct {
+	Near float64
+	Far  float64
+}
+
+func (c Coward) Velocity(e *Enemy, target geom.Vector) geom.Vector {
+	direction, distance := toward(e, target)
+	switch {
+	case distance < c.Near:
+		return direction.Scale(-e.speed())
+	case distance > c.Far:
+		return direction.Scale(math.Min(e.speed(), distance-c.Far))
+	default:
+		return direction.Perp().Scale(e.speed() * e.side)
+	}
+}
@@ -0,0 +1,91 @@
+package sprites
+
+import (
+	"math"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// fakeEnemy returns an enemy at the origin moving speed pixels a step, on
+// side, that has been alive for ticks.
+func fakeEnemy(speed, side float64, ticks int64) *Enemy {
+	return &Enemy{Type: &EnemyType{Speed: speed}, side: side, stateTicks: ticks}
+}
+
+func near(a, b geom.Vector) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+type behaviorTest struct {
+	name   string
+	enemy  *Enemy
+	target geom.Vector
+	want   geom.Vector
+}
+
+func runBehaviorTests(t *testing.T, b EnemyBehavior, tests []behaviorTest) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := b.Velocity(tt.enemy, tt.target); !near(got, tt.want) {
+			t.Errorf("%s: velocity %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChaser(t *testing.T) {
+	runBehaviorTests(t, Chaser{}, []behaviorTest{
+		{"right", fakeEnemy(2, 1, 0), geom.Vector{X: 100}, geom.Vector{X: 2}},
+		{"up", fakeEnemy(2, 1, 0), geom.Vector{Y: -50}, geom.Vector{Y: -2}},
+		{"diagonal", fakeEnemy(2, -1, 0), geom.Vector{X: 30, Y: 40}, geom.Vector{X: 1.2, Y: 1.6}},
+		{"no overshoot", fakeEnemy(2, 1, 0), geom.Vector{X: 0.5}, geom.Vector{X: 0.5}},
+		{"on the hero", fakeEnemy(2, 1, 0), geom.Vector{}, geom.Vector{}},
+	})
+}
+
+func TestFlanker(t *testing.T) {
+	f := Flanker{Angle: math.Pi / 2, Range: 100}
+	runBehaviorTests(t, f, []behaviorTest{
+		{"far, one side", fakeEnemy(2, 1, 0), geom.Vector{X: 500}, geom.Vector{Y: 2}},
+		{"far, other side", fakeEnemy(2, -1, 0), geom.Vector{X: 500}, geom.Vector{Y: -2}},
+		{"at range", fakeEnemy(2, 1, 0), geom.Vector{X: 100}, geom.Vector{X: 2}},
+		{"inside range", fakeEnemy(2, -1, 0), geom.Vector{Y: 50}, geom.Vector{Y: 2}},
+		{"no overshoot", fakeEnemy(2, 1, 0), geom.Vector{X: 1}, geom.Vector{X: 1}},
+	})
+}
+
+func TestZigZag(t *testing.T) {
+	z := ZigZag{Angle: math.Pi / 2, Ticks: 10}
+	runBehaviorTests(t, z, []behaviorTest{
+		{"first leg", fakeEnemy(2, 1, 0), geom.Vector{X: 500}, geom.Vector{Y: 2}},
+		{"end of first leg", fakeEnemy(2, 1, 9), geom.Vector{X: 500}, geom.Vector{Y: 2}},
+		{"second leg", fakeEnemy(2, 1, 10), geom.Vector{X: 500}, geom.Vector{Y: -2}},
+		{"third leg", fakeEnemy(2, 1, 25), geom.Vector{X: 500}, geom.Vector{Y: 2}},
+		{"other side", fakeEnemy(2, -1, 0), geom.Vector{X: 500}, geom.Vector{Y: -2}},
+		{"other side, second leg", fakeEnemy(2, -1, 15), geom.Vector{X: 500}, geom.Vector{Y: 2}},
+	})
+}
+
+func TestAmbusher(t *testing.T) {
+	a := Ambusher{WaitTicks: 10, DashTicks: 5, DashSpeed: 3}
+	runBehaviorTests(t, a, []behaviorTest{
+		{"waiting", fakeEnemy(2, 1, 0), geom.Vector{X: 500}, geom.Vector{}},
+		{"end of wait", fakeEnemy(2, 1, 9), geom.Vector{X: 500}, geom.Vector{}},
+		{"dashing", fakeEnemy(2, 1, 10), geom.Vector{X: 500}, geom.Vector{X: 6}},
+		{"end of dash", fakeEnemy(2, 1, 14), geom.Vector{Y: 500}, geom.Vector{Y: 6}},
+		{"waiting again", fakeEnemy(2, 1, 15), geom.Vector{X: 500}, geom.Vector{}},
+		{"dash does not overshoot", fakeEnemy(2, 1, 12), geom.Vector{X: 4}, geom.Vector{X: 4}},
+	})
+}
+
+func TestCoward(t *testing.T) {
+	c := Coward{Near: 100, Far: 200}
+	forward := geom.Vector{X: 1}
+	runBehaviorTests(t, c, []behaviorTest{
+		{"too close", fakeEnemy(2, 1, 0), geom.Vector{X: 50}, geom.Vector{X: -2}},
+		{"too far", fakeEnemy(2, 1, 0), geom.Vector{X: 500}, geom.Vector{X: 2}},
+		{"just too far", fakeEnemy(2, 1, 0), geom.Vector{X: 201}, geom.Vector{X: 1}},
+		{"comfortable", fakeEnemy(2, 1, 0), geom.Vector{X: 150}, forward.Perp().Scale(2)},
+		{"comfortable, other side", fakeEnemy(2, -1, 0), geom.Vector{X: 150}, forward.Perp().Scale(-2)},
+	})
+}
@@ -0,0 +1,291 @@
+package sprites
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+//go:embed bosses.json
+var defaultBossTypes []byte
+
+const (
+	// summonClearance is how close to the hero minions may be summoned,
+	// as close as new enemies may arrive in a stage
+	summonClearance float64 = 150
+)
+
+type AttackKind string
+
+const (
+	// Charge locks on to the hero and rushes at Speed for Ticks.
+	Charge AttackKind = "charge"
+	// Ring fires Projectiles evenly spaced all around the boss.
+	Ring AttackKind = "ring"
+	// Summon calls Minions of the Minion enemy type to the boss's side,
+	// as long as there are fewer than Limit enemies in the arena.
+	Summon AttackKind = "summon"
+)
+
+// BossAttack is an attack a boss makes every EveryTicks while in a phase.
+type BossAttack struct {
+	Kind       AttackKind `json:"kind"`
+	EveryTicks int64      `json:"every"`
+
+	Speed float64 `json:"speed"`
+	Ticks int64   `json:"ticks"`
+
+	Projectiles int     `json:"projectiles"`
+	Projectile  string  `json:"projectile"`
+	Range       float64 `json:"range"`
+
+	Minions int    `json:"minions"`
+	Minion  string `json:"minion"`
+	Limit   int    `json:"limit"`
+
+	projectile ArrowKind
+	minion     *EnemyType
+}
+
+// BossPhase is how a boss fights once its health has dropped to Health, a
+// fraction of its hitpoints.
+type BossPhase struct {
+	Health   float64      `json:"health"`
+	Behavior string       `json:"behavior"`
+	Speed    float64      `json:"speed"`
+	Attacks  []BossAttack `json:"attacks"`
+
+	behavior EnemyBehavior
+}
+
+// BossType describes a boss. It looks like the Enemy type drawn Scale times
+// larger and works through its Phases in order as it is hurt.
+type BossType struct {
+	Name      string      `json:"name"`
+	Title     string      `json:"title"`
+	Subtitle  string      `json:"subtitle"`
+	Enemy     string      `json:"enemy"`
+	Hitpoints int         `json:"hitpoints"`
+	Scale     float64     `json:"scale"`
+	Phases    []BossPhase `json:"phases"`
+
+	enemy *EnemyType
+}
+
+// BossTypes holds boss types by name.
+type BossTypes map[string]*BossType
+
+func (a *BossAttack) validate(enemyTypes EnemyTypes) error {
+	if a.EveryTicks <= 0 {
+		return fmt.Errorf("%s attack: every must be positive", a.Kind)
+	}
+	switch a.Kind {
+	case Charge:
+		if a.Speed <= 0 || a.Ticks <= 0 {
+			return fmt.Errorf("charge attack: speed and ticks must be positive")
+		}
+	case Ring:
+		if a.Projectile == "" {
+			a.Projectile = "arrow"
+		}
+		projectile, ok := projectiles[a.Projectile]
+		if !ok {
+			return fmt.Errorf("ring attack: unknown projectile %q", a.Projectile)
+		}
+		a.projectile = projectile
+		if a.Projectiles <= 0 || a.Speed <= 0 || a.Range <= 0 {
+			return fmt.Errorf("ring attack: projectiles, speed and range must be positive")
+		}
+	case Summon:
+		minion, ok := enemyTypes[a.Minion]
+		if !ok {
+			return fmt.Errorf("summon attack: unknown enemy type %q", a.Minion)
+		}
+		a.minion = minion
+		if a.Minions <= 0 {
+			return fmt.Errorf("summon attack: minions must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown attack %q", a.Kind)
+	}
+	return nil
+}
+
+func (t *BossType) validate(enemyTypes EnemyTypes) error {
+	enemy, ok := enemyTypes[t.Enemy]
+	if !ok {
+		return fmt.Errorf("boss %s: unknown enemy type %q", t.Name, t.Enemy)
+	}
+	t.enemy = enemy
+	if t.Hitpoints <= 0 {
+		return fmt.Errorf("boss %s: hitpoints must be positive", t.Name)
+	}
+	if t.Scale <= 0 {
+		t.Scale = 1
+	}
+	if len(t.Phases) == 0 {
+		return fmt.Errorf("boss %s: needs at least one phase", t.Name)
+	}
+	for i := range t.Phases {
+		p := &t.Phases[i]
+		if i > 0 && p.Health >= t.Phases[i-1].Health {
+			return fmt.Errorf("boss %s: phases must be in order of falling health", t.Name)
+		}
+		behavior, ok := Behavior(p.Behavior)
+		if !ok {
+			return fmt.Errorf("boss %s: unknown behavior %q", t.Name, p.Behavior)
+		}
+		p.behavior = behavior
+		if p.Speed <= 0 {
+			return fmt.Errorf("boss %s: phase speed must be positive", t.Name)
+		}
+		for j := range p.Attacks {
+			if err := p.Attacks[j].validate(enemyTypes); err != nil {
+				return fmt.Errorf("boss %s: %w", t.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads a JSON list of boss types from r and adds them to types. The
+// enemy types they look like and summon must be in enemyTypes.
+func (types BossTypes) Load(r io.Reader, enemyTypes EnemyTypes) error {
+	loaded := []*BossType{}
+	if err := json.NewDecoder(r).Decode(&loaded); err != nil {
+		return fmt.Errorf("boss types: %w", err)
+	}
+	for _, t := range loaded {
+		if err := t.validate(enemyTypes); err != nil {
+			return err
+		}
+		types[t.Name] = t
+	}
+	return nil
+}
+
+// DefaultBossTypes returns the bosses built into the game.
+func DefaultBossTypes(enemyTypes EnemyTypes) BossTypes {
+	types := BossTypes{}
+	if err := types.Load(bytes.NewReader(defaultBossTypes), enemyTypes); err != nil {
+		log.Fatal(err)
+	}
+	return types
+}
+
+// EnemyType returns the enemy type the boss looks like.
+func (t *BossType) EnemyType() *EnemyType {
+	return t.enemy
+}
+
+// Boss is the fight state of an enemy that is a boss.
+type Boss struct {
+	Type *BossType
+
+	phase       int
+	cooldowns   []int64
+	charge      geom.Vector
+	chargeTicks int64
+	rings       int
+}
+
+func newBoss(t *BossType) *Boss {
+	b := &Boss{Type: t}
+	b.enterPhase(0)
+	return b
+}
+
+func (b *Boss) enterPhase(phase int) {
+	b.phase = phase
+	b.cooldowns = b.cooldowns[:0]
+	for _, a := range b.Type.Phases[phase].Attacks {
+		b.cooldowns = append(b.cooldowns, a.EveryTicks)
+	}
+	b.chargeTicks = 0
+}
+
+// Phase returns the index of the phase the boss is fighting in.
+func (b *Boss) Phase() int {
+	return b.phase
+}
+
+func (b *Boss) current() *BossPhase {
+	return &b.Type.Phases[b.phase]
+}
+
+// IsCharging reports whether the boss is in the middle of a charge.
+func (b *Boss) IsCharging() bool {
+	return b.chargeTicks > 0
+}
+
+// velocity returns how the boss moves this step.
+func (b *Boss) velocity(e *Enemy, target geom.Vector) geom.Vector {
+	if b.chargeTicks > 0 {
+		b.chargeTicks = b.chargeTicks - 1
+		return b.charge
+	}
+	return b.current().behavior.Velocity(e, target)
+}
+
+func (b *Boss) updatePhase(health float64) {
+	for b.phase+1 < len(b.Type.Phases) && health <= b.Type.Phases[b.phase+1].Health {
+		b.enterPhase(b.phase + 1)
+	}
+}
+
+// Attack makes every attack of the current phase that is ready. Ring shots
+// go into arrows and summoned minions into enemies, on open floor and no
+// closer than summonClearance to the hero at target.
+func (b *Boss) Attack(e *Enemy, target geom.Vector, arrows *ArrowStore, enemies *EnemyStore, walls *tilemap.Map, rng *rand.Rand) {
+	if !e.IsAlive() {
+		return
+	}
+	b.updatePhase(e.Health())
+	for i := range b.current().Attacks {
+		a := &b.current().Attacks[i]
+		b.cooldowns[i] = b.cooldowns[i] - 1
+		if b.cooldowns[i] > 0 || b.chargeTicks > 0 {
+			continue
+		}
+		b.cooldowns[i] = a.EveryTicks
+		switch a.Kind {
+		case Charge:
+			b.charge = target.Sub(e.position).Normalize().Scale(a.Speed)
+			b.chargeTicks = a.Ticks
+		case Ring:
+			b.rings = b.rings + 1
+			step := 2 * math.Pi / float64(a.Projectiles)
+			// every other ring is turned half a step so there is no safe
+			// line to stand on
+			offset := float64(b.rings%2) * step / 2
+			for j := 0; j < a.Projectiles; j++ {
+				direction := geom.FromAngle(offset + (step * float64(j)))
+				arrow := arrows.Spawn(NoOwner, a.projectile, e.position, direction, a.Speed, a.Range)
+				arrow.Sprite.Tint = enemyArrowTint
+			}
+		case Summon:
+			clear := func(p geom.Vector) bool {
+				return p.Sub(target).Length() >= summonClearance
+			}
+			for j := 0; j < a.Minions && (a.Limit == 0 || enemies.Len() < a.Limit); j++ {
+				angle := 2 * math.Pi * float64(j) / float64(a.Minions)
+				at := e.position.Add(geom.FromAngle(angle).Scale(float64(e.Sprite.FrameWidth()) * b.Type.Scale / 2))
+				at = walls.NearestOpen(at, clear)
+				if !clear(at) {
+					// a minion called up right next to the hero is not
+					// summoned at all
+					continue
+				}
+				enemies.Spawn(a.minion, int(math.Round(at.X)), int(math.Round(at.Y)), a.minion.Hitpoints, rng)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package sprites
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+const testBosses = `[
+	{
+		"name": "tester",
+		"enemy": "goblin",
+		"hitpoints": 100,
+		"phases": [
+			{"health": 1, "behavior": "chaser", "speed": 1, "attacks": [
+				{"kind": "ring", "every": 3, "projectiles": 4, "speed": 2, "range": 100},
+				{"kind": "summon", "every": 5, "minions": 2, "minion": "goblin", "limit": 5}
+			]},
+			{"health": 0.75, "behavior": "chaser", "speed": 1, "attacks": [
+				{"kind": "ring", "every": 7, "projectiles": 4, "speed": 2, "range": 100}
+			]},
+			{"health": 0.5, "behavior": "chaser", "speed": 1, "attacks": [
+				{"kind": "ring", "every": 11, "projectiles": 4, "speed": 2, "range": 100}
+			]},
+			{"health": 0.25, "behavior": "chaser", "speed": 1, "attacks": [
+				{"kind": "charge", "every": 4, "speed": 5, "ticks": 3},
+				{"kind": "ring", "every": 2, "projectiles": 4, "speed": 2, "range": 100}
+			]}
+		]
+	}
+]`
+
+type bossFight struct {
+	boss    *Enemy
+	hero    *Sprite
+	arrows  *ArrowStore
+	enemies *EnemyStore
+	rng     *rand.Rand
+}
+
+func newBossFight(t *testing.T) *bossFight {
+	t.Helper()
+	types := BossTypes{}
+	if err := types.Load(strings.NewReader(testBosses), DefaultEnemyTypes()); err != nil {
+		t.Fatal(err)
+	}
+	f := &bossFight{
+		hero:    &Sprite{X: 500, Y: 900},
+		arrows:  NewArrowStore(),
+		enemies: NewEnemyStore(),
+		rng:     rand.New(rand.NewSource(1)),
+	}
+	f.boss = f.enemies.SpawnBoss(types["tester"], 500, 100, f.rng)
+	return f
+}
+
+// attack takes the boss through one tick the way Update and the world do,
+// stepping any charge along before it attacks.
+func (f *bossFight) attack() {
+	target := geom.Vector{X: float64(f.hero.X), Y: float64(f.hero.Y)}
+	f.boss.Boss.velocity(f.boss, target)
+	f.boss.Boss.Attack(f.boss, target, f.arrows, f.enemies, nil, f.rng)
+}
+
+func TestBossPhases(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage []int
+		phase  int
+	}{
+		{"unhurt", nil, 0},
+		{"scratched", []int{10}, 0},
+		{"at the first threshold", []int{25}, 1},
+		{"two thresholds in one hit", []int{60}, 2},
+		{"every threshold in one hit", []int{80}, 3},
+		{"one threshold at a time", []int{30, 25, 30}, 3},
+	}
+	for _, tt := range tests {
+		f := newBossFight(t)
+		for _, d := range tt.damage {
+			f.boss.Shot(d, f.hero)
+			f.attack()
+		}
+		if got := f.boss.Boss.Phase(); got != tt.phase {
+			t.Errorf("%s: phase %d, want %d", tt.name, got, tt.phase)
+		}
+	}
+}
+
+// attackTicks attacks once a tick for ticks and returns the ticks on which
+// arrows were fired and minions summoned.
+func (f *bossFight) attackTicks(ticks int) ([]int, []int) {
+	rings, summons := []int{}, []int{}
+	for tick := 1; tick <= ticks; tick++ {
+		arrows, enemies := f.arrows.Len(), f.enemies.Len()
+		f.attack()
+		if f.arrows.Len() > arrows {
+			rings = append(rings, tick)
+		}
+		if f.enemies.Len() > enemies {
+			summons = append(summons, tick)
+		}
+	}
+	return rings, summons
+}
+
+func TestBossAttackCooldowns(t *testing.T) {
+	f := newBossFight(t)
+	rings, summons := f.attackTicks(15)
+	if want := []int{3, 6, 9, 12, 15}; !reflect.DeepEqual(rings, want) {
+		t.Errorf("rings fired on ticks %v, want %v", rings, want)
+	}
+	// the second summon brings the arena to its limit of five enemies
+	if want := []int{5, 10}; !reflect.DeepEqual(summons, want) {
+		t.Errorf("minions summoned on ticks %v, want %v", summons, want)
+	}
+	if got := f.enemies.Len(); got != 5 {
+		t.Errorf("%d enemies after summoning, want 5", got)
+	}
+	if got := f.arrows.Len(); got != 5*4 {
+		t.Errorf("%d arrows after five rings, want 20", got)
+	}
+}
+
+func TestBossPhaseResetsCooldowns(t *testing.T) {
+	f := newBossFight(t)
+	f.attackTicks(2)
+	f.boss.Shot(30, f.hero)
+	rings, _ := f.attackTicks(14)
+	if want := []int{7, 14}; !reflect.DeepEqual(rings, want) {
+		t.Errorf("rings fired on ticks %v of the new phase, want %v", rings, want)
+	}
+}
+
+func TestBossChargeHoldsAttacks(t *testing.T) {
+	f := newBossFight(t)
+	f.boss.Shot(80, f.hero)
+	rings, _ := f.attackTicks(12)
+	// the ring is ready every other tick but waits out the charges that
+	// start on ticks 4 and 8 and last three ticks each
+	if want := []int{2, 7, 11}; !reflect.DeepEqual(rings, want) {
+		t.Errorf("rings fired on ticks %v, want %v", rings, want)
+	}
+}
+
+func TestSummonKeepsClear(t *testing.T) {
+	rows := []string{}
+	for i := 0; i < 5; i++ {
+		rows = append(rows, strings.Repeat(".", 25))
+	}
+	// both minions of the test boss would land in the wall it stands in
+	rows[2] = strings.Repeat(".", 12) + "#" + strings.Repeat(".", 12)
+	walls, err := tilemap.Parse(rows, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newBossFight(t)
+	for i := 0; i < 5; i++ {
+		f.boss.Boss.velocity(f.boss, geom.Vector{X: 500, Y: 900})
+		f.boss.Boss.Attack(f.boss, geom.Vector{X: 500, Y: 900}, f.arrows, f.enemies, walls, f.rng)
+	}
+	if got := f.enemies.Len(); got != 3 {
+		t.Fatalf("%d enemies after a summon, want 3", got)
+	}
+	for _, e := range f.enemies.All()[1:] {
+		if walls.IsSolid(e.Position()) {
+			t.Errorf("minion summoned into the wall at %v", e.Position())
+		}
+	}
+
+	f = newBossFight(t)
+	f.hero.Y = 180
+	f.attackTicks(5)
+	if got := f.enemies.Len(); got != 1 {
+		t.Errorf("%d enemies after summoning next to the hero, want just the boss", got)
+	}
+}
+
+func TestLoadBossTypes(t *testing.T) {
+	types := BossTypes{}
+	if err := types.Load(strings.NewReader(testBosses), DefaultEnemyTypes()); err != nil {
+		t.Fatal(err)
+	}
+	b := types["tester"]
+	if b == nil || b.Scale != 1 || b.EnemyType().Name != "goblin" || len(b.Phases) != 4 {
+		t.Fatalf("loaded %+v", b)
+	}
+	if a := b.Phases[0].Attacks; a[0].projectile != PlainArrow || a[1].minion.Name != "goblin" {
+		t.Errorf("first phase attacks loaded as %+v", a)
+	}
+}
+
+func TestLoadBossTypeErrors(t *testing.T) {
+	phase := func(attacks string) string {
+		return `[{"name": "b", "enemy": "goblin", "hitpoints": 10, "phases": [{"health": 1, "behavior": "chaser", "speed": 1, "attacks": [` + attacks + `]}]}]`
+	}
+	tests := []struct {
+		name   string
+		bosses string
+		want   string
+	}{
+		{"bad json", `[{"name": 1}]`, "boss types:"},
+		{"unknown enemy", `[{"name": "b", "enemy": "dragon", "hitpoints": 10}]`, `unknown enemy type "dragon"`},
+		{"no hitpoints", `[{"name": "b", "enemy": "goblin"}]`, "hitpoints must be positive"},
+		{"no phases", `[{"name": "b", "enemy": "goblin", "hitpoints": 10}]`, "needs at least one phase"},
+		{"phases out of order", `[{"name": "b", "enemy": "goblin", "hitpoints": 10, "phases": [
+			{"health": 0.5, "behavior": "chaser", "speed": 1}, {"health": 1, "behavior": "chaser", "speed": 1}]}]`, "order of falling health"},
+		{"unknown behavior", `[{"name": "b", "enemy": "goblin", "hitpoints": 10, "phases": [{"health": 1, "behavior": "dancer", "speed": 1}]}]`, `unknown behavior "dancer"`},
+		{"no phase speed", `[{"name": "b", "enemy": "goblin", "hitpoints": 10, "phases": [{"health": 1, "behavior": "chaser"}]}]`, "phase speed must be positive"},
+		{"unknown attack", phase(`{"kind": "dance", "every": 1}`), `unknown attack "dance"`},
+		{"attack without every", phase(`{"kind": "ring", "projectiles": 4, "speed": 2, "range": 100}`), "every must be positive"},
+		{"charge without ticks", phase(`{"kind": "charge", "every": 5, "speed": 2}`), "speed and ticks must be positive"},
+		{"ring without range", phase(`{"kind": "ring", "every": 5, "projectiles": 4, "speed": 2}`), "projectiles, speed and range must be positive"},
+		{"ring of rocks", phase(`{"kind": "ring", "every": 5, "projectile": "rock", "projectiles": 4, "speed": 2, "range": 100}`), `unknown projectile "rock"`},
+		{"summon nobody", phase(`{"kind": "summon", "every": 5, "minions": 2, "minion": "dragon"}`), `unknown enemy type "dragon"`},
+		{"summon no minions", phase(`{"kind": "summon", "every": 5, "minion": "goblin"}`), "minions must be positive"},
+	}
+	for _, tt := range tests {
+		err := BossTypes{}.Load(strings.NewReader(tt.bosses), DefaultEnemyTypes())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package sprites
+
+// The game is simulated in fixed steps rather than wall clock time. Ebiten
+// calls Update TicksPerSecond times a second, so timers are kept in ticks
+// and only advance while the simulation is running.
+const (
+	TicksPerSecond int64 = 60
+)
@@ -3,18 +3,9 @@ package sprites
 import (
 	"math"
 	"math/rand"
-	"time"
 
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/markrzasa/arrowsaway/images"
-)
-
-const (
-	healthMargin int     = 2
-	imageWidth   int     = 32
-	scaleFactor  float64 = 0.25
-
-	HitpointIncrement int = 50
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/tilemap"
 )
 
 type enemyState int
@@ -26,112 +17,171 @@ const (
 )
 
 type Enemy struct {
-	healthBar                 *Sprite
+	Type                      *EnemyType
 	Sprite                    *Sprite
-	startX, startY, frame     int
+	startX, startY            int
+	position                  geom.Vector
+	side                      float64
+	reload                    int64
 	state                     enemyState
-	stateTime                 int64
+	stateTicks                int64
 	hitpoints, totalHitpoints int
-	boss                      bool
+	rng                       *rand.Rand
+
+	// Boss is set for enemies that are bosses.
+	Boss *Boss
 }
 
 func (e *Enemy) setScale() {
 	scale := 1.0
 	if e.state == Alive {
-		if e.boss {
-			scale = math.Max(1, float64(e.hitpoints)/200.0)
+		if e.Boss != nil {
+			scale = e.Boss.Type.Scale
 		} else {
-			scale = 1 + ((float64(e.totalHitpoints/HitpointIncrement) - 1) * scaleFactor)
+			scale = 1 + ((float64(e.totalHitpoints/e.Type.Hitpoints) - 1) * e.Type.ScaleStep)
 		}
 	}
-	e.healthBar.ScaleX = scale
 	e.Sprite.Scale(scale)
 }
 
 func (e *Enemy) setState(state enemyState) {
 	e.state = state
-	e.stateTime = time.Now().Unix()
+	e.stateTicks = 0
 }
 
-func (e *Enemy) moveTowardHero(hero *Sprite) {
-	if e.Sprite.X != hero.X {
-		if e.Sprite.X < hero.X {
-			e.Sprite.X = e.Sprite.X + 1
-		} else if e.Sprite.X > hero.X {
-			e.Sprite.X = e.Sprite.X - 1
-		}
-	}
-	if e.Sprite.Y != hero.Y {
-		if e.Sprite.Y < hero.Y {
-			e.Sprite.Y = e.Sprite.Y + 1
-		} else if e.Sprite.Y > hero.Y {
-			e.Sprite.Y = e.Sprite.Y - 1
-		}
+func (e *Enemy) speed() float64 {
+	if e.Boss != nil {
+		return e.Boss.current().Speed
 	}
+	return e.Type.Speed
+}
+
+// Position returns where the enemy is, to a fraction of a pixel.
+func (e *Enemy) Position() geom.Vector {
+	return e.position
+}
+
+// radius is how far from its middle the enemy keeps from walls.
+func (e *Enemy) radius() float64 {
+	return float64(e.Sprite.FrameWidth()) * e.Sprite.ScaleX / 4
+}
+
+func (e *Enemy) setPosition(position geom.Vector) {
+	e.position = position
+	e.Sprite.X = int(math.Round(position.X))
+	e.Sprite.Y = int(math.Round(position.Y))
 }
 
 func (e *Enemy) moveAwayFromHero(hero *Sprite) {
-	if e.Sprite.X != hero.X {
-		if e.Sprite.X < hero.X {
-			e.Sprite.X = e.Sprite.X - 1
-		} else if e.Sprite.X > hero.X {
-			e.Sprite.X = e.Sprite.X + 1
-		}
+	away := e.position.Sub(geom.Vector{X: float64(hero.X), Y: float64(hero.Y)})
+	e.setPosition(e.position.Add(away.Normalize()))
+}
+
+// route returns where the enemy heads for to reach target. With a wall in
+// the way it follows paths around it instead, heading for a point as far
+// off as the hero is along the path so behaviors judge distance the same.
+func (e *Enemy) route(target geom.Vector, walls *tilemap.Map, paths *tilemap.Field) geom.Vector {
+	if walls.Clear(e.position, target) {
+		return target
+	}
+	if direction, distance, ok := paths.Toward(e.position); ok {
+		return e.position.Add(direction.Scale(distance))
 	}
-	if e.Sprite.Y != hero.Y {
-		if e.Sprite.Y < hero.Y {
-			e.Sprite.Y = e.Sprite.Y - 1
-		} else if e.Sprite.Y > hero.Y {
-			e.Sprite.Y = e.Sprite.Y + 1
+	return target
+}
+
+func (e *Enemy) move(hero *Sprite, push geom.Vector, width, height int, walls *tilemap.Map, paths *tilemap.Field) {
+	target := e.route(geom.Vector{X: float64(hero.X), Y: float64(hero.Y)}, walls, paths)
+	var velocity geom.Vector
+	if e.Boss != nil {
+		velocity = e.Boss.velocity(e, target)
+	} else {
+		if e.rng.Float64() >= e.Type.MoveChance {
+			return
 		}
+		velocity = e.Type.behavior.Velocity(e, target)
 	}
+	next := walls.Move(e.position, velocity.Add(push.Scale(e.speed())), e.radius())
+	// enemies placed off screen may walk in but none may walk out
+	next.X = math.Max(math.Min(0, e.position.X), math.Min(math.Max(float64(width), e.position.X), next.X))
+	next.Y = math.Max(math.Min(0, e.position.Y), math.Min(math.Max(float64(height), e.position.Y), next.Y))
+	e.setPosition(next)
 }
 
-func (e *Enemy) move(hero *Sprite) {
- 	if rand.Intn(2) > 0 {
-		e.moveTowardHero(hero)
+func (e *Enemy) nearest(heroes []*Sprite) *Sprite {
+	var nearest *Sprite
+	nearestDistance := math.MaxFloat64
+	for _, h := range heroes {
+		d := math.Hypot(float64(h.X-e.Sprite.X), float64(h.Y-e.Sprite.Y))
+		if d < nearestDistance {
+			nearest = h
+			nearestDistance = d
+		}
 	}
+	return nearest
 }
 
-func (e *Enemy) Update(width, height int, hero *Sprite) {
+// Update advances the enemy one step. push steers it away from the enemies
+// crowding it and is scaled by the enemy's speed, walls block its way and
+// paths, searched toward the heroes, leads it around them.
+func (e *Enemy) Update(width, height int, heroes []*Sprite, push geom.Vector, walls *tilemap.Map, paths *tilemap.Field) {
+	hero := e.nearest(heroes)
+	e.stateTicks = e.stateTicks + 1
 	switch e.state {
 	case Alive:
-		e.move(hero)
-		offset := int((time.Now().Unix() - e.stateTime) % 3)
-		e.frame = offset
+		if hero != nil {
+			e.move(hero, push, width, height, walls, paths)
+		}
+		if e.reload > 0 {
+			e.reload = e.reload - 1
+		}
+		e.Sprite.Frame = int((e.stateTicks / e.Type.TicksPerFrame) % int64(e.Type.WalkFrames))
 	case Dead:
-		if time.Now().Unix() > (e.stateTime + 2) {
+		if e.stateTicks > e.Type.DeathTicks {
 			e.setState(Buried)
 		} else {
-			e.frame = 3
+			e.Sprite.Frame = e.Type.DeadFrame
 		}
 	}
-	deltaX := hero.X - e.Sprite.X
-	deltaY := hero.Y - e.Sprite.Y
-	if e.IsAlive() {
+	if e.IsAlive() && hero != nil {
+		deltaX := hero.X - e.Sprite.X
+		deltaY := hero.Y - e.Sprite.Y
 		e.Sprite.Radians = math.Atan2(float64(deltaY), float64(deltaX)) - (math.Pi / 180)
 		e.setScale()
 	}
 }
 
-func (e *Enemy) Draw(screen *ebiten.Image) {
-	e.Sprite.Draw(screen, e.frame)
-
-	if e.IsAlive() {
-		scaledBounds := e.Sprite.ScaledBounds()
-		e.healthBar.X = e.Sprite.X
-		e.healthBar.Y = scaledBounds.Max.Y + healthMargin
-		if e.healthBar.Y > screen.Bounds().Dy() {
-			e.healthBar.Y = scaledBounds.Min.Y - healthMargin
-		}
-		subImageWidth := int(float64(e.healthBar.imageWidth) * (float64(e.hitpoints)/float64(e.totalHitpoints)))
-		e.healthBar.DrawSubImage(screen, subImageWidth)
+// Fire shoots at a hero at target moving by velocity every step, adding
+// the projectile to arrows. It reports whether the enemy fired, which it
+// only does when it shoots, is alive, has reloaded and the hero is in range.
+func (e *Enemy) Fire(target, velocity geom.Vector, arrows *ArrowStore) bool {
+	t := e.Type
+	if !t.IsRanged() || !e.IsAlive() || e.reload > 0 {
+		return false
+	}
+	if target.Sub(e.position).Length() > t.ProjectileRange {
+		return false
 	}
+	lead := geom.InterceptTime(e.position, target, velocity, t.ProjectileSpeed) * t.AimLead
+	aim := target.Add(velocity.Scale(lead)).Sub(e.position)
+	if aim.IsZero() {
+		return false
+	}
+	arrow := arrows.Spawn(NoOwner, t.projectile, e.position, aim, t.ProjectileSpeed, t.ProjectileRange)
+	arrow.Sprite.Tint = enemyArrowTint
+	e.reload = t.FireTicks
+	return true
+}
+
+// Health returns the fraction of the enemy's hitpoints it has left.
+func (e *Enemy) Health() float64 {
+	return float64(e.hitpoints) / float64(e.totalHitpoints)
 }
 
-func (e *Enemy) Shot(hero *Sprite) {
-	e.hitpoints = e.hitpoints - 1
-	if e.hitpoints == 0 {
+func (e *Enemy) Shot(damage int, hero *Sprite) {
+	e.hitpoints = e.hitpoints - damage
+	if e.hitpoints <= 0 {
+		e.hitpoints = 0
 		e.setState(Dead)
 	} else {
 		e.moveAwayFromHero(hero)
@@ -146,45 +196,72 @@ func (e *Enemy) IsBuried() bool {
 	return e.state == Buried
 }
 
+// ToStart sends the enemy back to where it arrived.
 func (e *Enemy) ToStart() {
-	e.Sprite.X = e.startX
-	e.Sprite.Y = e.startY
-}
-
-func (e *Enemy) IsHit(arrow *Arrow, hero *Sprite) (bool, bool) {
-	hitWhileAlive := false
-	hit := false
-	b := e.Sprite.ScaledBounds()
-	if b.Min.X <= arrow.Sprite.X && arrow.Sprite.X <= b.Max.X && b.Min.Y <= arrow.Sprite.Y && arrow.Sprite.Y <= b.Max.Y {
-		hit = true
-		if e.IsAlive() {
-			hitWhileAlive = true
-			e.Shot(hero)
-		}
+	e.setPosition(geom.Vector{X: float64(e.startX), Y: float64(e.startY)})
+}
+
+// MoveStart changes where the enemy arrived, and is sent back to, to x, y.
+func (e *Enemy) MoveStart(x, y int) {
+	e.startX = x
+	e.startY = y
+}
+
+// Sweep reports whether the arrow touched the enemy anywhere along the path
+// it took during its last update and how far along that path it first did.
+func (e *Enemy) Sweep(arrow *Arrow) (float64, bool) {
+	return geom.SegmentShape(arrow.Previous, arrow.Position, e.Sprite.Shape())
+}
+
+// Hit applies an arrow that struck the enemy and reports whether the enemy
+// was still alive to take the damage.
+func (e *Enemy) Hit(arrow *Arrow, hero *Sprite) bool {
+	if !e.IsAlive() {
+		return false
 	}
+	e.Shot(arrow.Damage, hero)
+	return true
+}
+
+// InBlast reports whether any part of the enemy is within radius of center.
+func (e *Enemy) InBlast(center geom.Vector, radius float64) bool {
+	return e.Sprite.Shape().Distance(center) <= radius
+}
 
-	return hitWhileAlive, hit
+// NewEnemy creates an enemy of type t at x, y with hp hitpoints.
+func NewEnemy(t *EnemyType, x, y, hp int, rng *rand.Rand) *Enemy {
+	enemy := &Enemy{Sprite: &Sprite{}}
+	enemy.reset(t, x, y, hp, rng)
+	return enemy
 }
 
-func NewEnemy(x, y, hp int, boss bool, image *ebiten.Image) *Enemy {
-	health := images.GetImages().EnemyHealth
-	enemy := &Enemy{
+func (e *Enemy) makeBoss(t *BossType) {
+	e.Boss = newBoss(t)
+	e.setScale()
+}
+
+func (e *Enemy) reset(t *EnemyType, x, y, hp int, rng *rand.Rand) {
+	*e = Enemy{
+		Type:           t,
 		startX:         x,
 		startY:         y,
 		state:          Alive,
-		stateTime:      time.Now().Unix(),
-		frame:          0,
+		stateTicks:     0,
 		hitpoints:      hp,
 		totalHitpoints: hp,
-		boss:           boss,
-		healthBar:      NewSprite(health.Bounds().Dx(), health),
-		Sprite:         NewSprite(imageWidth, image),
-	}
-	enemy.Sprite.X = x
-	enemy.startX = enemy.Sprite.X
-	enemy.Sprite.Y = y
-	enemy.startY = enemy.Sprite.Y
-	enemy.Sprite.Radians = 0
-	enemy.setScale()
-	return enemy
+		rng:            rng,
+		Sprite:         e.Sprite,
+	}
+	e.Sprite.reset(t.FrameWidth, t.Image)
+	e.setPosition(geom.Vector{X: float64(x), Y: float64(y)})
+	e.side = 1
+	if rng.Intn(2) == 0 {
+		e.side = -1
+	}
+	if t.IsRanged() {
+		e.reload = t.FireTicks + rng.Int63n(t.FireTicks)
+	}
+	e.Sprite.Radians = 0
+	e.Sprite.SetHitbox(t.hitbox)
+	e.setScale()
 }
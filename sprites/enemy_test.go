@@ -0,0 +1,137 @@
+package sprites
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/tilemap"
+)
+
+func TestEnemyRoutesAroundWall(t *testing.T) {
+	rows := []string{}
+	for i := 0; i < 25; i++ {
+		rows = append(rows, strings.Repeat(".", 25))
+	}
+	// a wall down the middle of the arena with a gap at either end
+	for i := 5; i < 20; i++ {
+		rows[i] = strings.Repeat(".", 12) + "#" + strings.Repeat(".", 12)
+	}
+	walls, err := tilemap.Parse(rows, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hero := &Sprite{X: 700, Y: 500}
+	heroes := []*Sprite{hero}
+
+	tests := []struct {
+		name    string
+		paths   []geom.Vector
+		reaches bool
+	}{
+		{"following paths", []geom.Vector{{X: 700, Y: 500}}, true},
+		{"without paths", nil, false},
+	}
+	for _, tt := range tests {
+		paths := tilemap.Field{}
+		if tt.paths != nil {
+			paths.Update(walls, tt.paths)
+		}
+		e := NewEnemy(DefaultEnemyTypes()["goblin"], 300, 500, 1, rand.New(rand.NewSource(1)))
+		for i := 0; i < 4000; i++ {
+			e.Update(1000, 1000, heroes, geom.Vector{}, walls, &paths)
+			if walls.IsSolid(e.Position()) {
+				t.Fatalf("%s: enemy walked into the wall at %v", tt.name, e.Position())
+			}
+		}
+		reached := e.Position().Sub(geom.Vector{X: 700, Y: 500}).Length() < 1
+		if reached != tt.reaches {
+			t.Errorf("%s: enemy ended up at %v, reached the hero %v, want %v", tt.name, e.Position(), reached, tt.reaches)
+		}
+	}
+}
+
+// archer returns a goblin that shoots arrows at 10 pixels a step every 5
+// ticks, leading a moving hero by lead.
+func archer(lead float64) *Enemy {
+	t := *DefaultEnemyTypes()["goblin"]
+	t.FireTicks = 5
+	t.ProjectileSpeed = 10
+	t.ProjectileRange = 300
+	t.AimLead = lead
+	t.projectile = PlainArrow
+	return NewEnemy(&t, 0, 0, 1, rand.New(rand.NewSource(1)))
+}
+
+func TestFire(t *testing.T) {
+	tests := []struct {
+		name     string
+		enemy    *Enemy
+		target   geom.Vector
+		velocity geom.Vector
+		aim      geom.Vector
+		fired    bool
+	}{
+		{"standing still", archer(1), geom.Vector{X: 100}, geom.Vector{}, geom.Vector{X: 1}, true},
+		{"standing still, no lead", archer(0), geom.Vector{X: 100}, geom.Vector{}, geom.Vector{X: 1}, true},
+		// the arrow covers 2 of the hero's steps for every one, meeting
+		// it 30 degrees off the line to it
+		{"moving", archer(1), geom.Vector{X: 100}, geom.Vector{Y: 5}, geom.FromAngle(math.Pi / 6), true},
+		{"moving, no lead", archer(0), geom.Vector{X: 100}, geom.Vector{Y: 5}, geom.Vector{X: 1}, true},
+		{"moving, half lead", archer(0.5), geom.Vector{X: 100}, geom.Vector{Y: 5}, geom.Vector{X: 100, Y: 2.5 * math.Sqrt(10000.0/75)}.Normalize(), true},
+		// the hero outruns arrows, so they aim where it will be by the
+		// time an arrow reaches where it is now
+		{"too fast to catch", archer(1), geom.Vector{X: 100}, geom.Vector{Y: 20}, geom.Vector{X: 100, Y: 200}.Normalize(), true},
+		{"out of range", archer(1), geom.Vector{X: 301}, geom.Vector{}, geom.Vector{}, false},
+		{"on top of the hero", archer(1), geom.Vector{}, geom.Vector{}, geom.Vector{}, false},
+		{"not an archer", NewEnemy(DefaultEnemyTypes()["goblin"], 0, 0, 1, rand.New(rand.NewSource(1))), geom.Vector{X: 100}, geom.Vector{}, geom.Vector{}, false},
+	}
+	for _, tt := range tests {
+		arrows := NewArrowStore()
+		// skip the wait archers start with
+		tt.enemy.reload = 0
+		fired := tt.enemy.Fire(tt.target, tt.velocity, arrows)
+		if fired != tt.fired || arrows.Len() != map[bool]int{true: 1}[tt.fired] {
+			t.Errorf("%s: fired %v with %d arrows, want %v", tt.name, fired, arrows.Len(), tt.fired)
+			continue
+		}
+		if fired && !near(arrows.All()[0].Direction, tt.aim) {
+			t.Errorf("%s: aimed at %v, want %v", tt.name, arrows.All()[0].Direction, tt.aim)
+		}
+	}
+}
+
+func TestFireCooldown(t *testing.T) {
+	e := archer(1)
+	arrows := NewArrowStore()
+	fired := []int{}
+	for tick := 1; tick <= 20; tick++ {
+		if e.Fire(geom.Vector{X: 100}, geom.Vector{}, arrows) {
+			fired = append(fired, tick)
+		}
+		e.Update(1000, 1000, nil, geom.Vector{}, nil, nil)
+	}
+	// archers start with between one and two reloads to wait, so that
+	// those arriving together do not all shoot at once
+	if len(fired) == 0 || fired[0] < 6 || fired[0] > 11 {
+		t.Fatalf("first fired on ticks %v, want between 6 and 11", fired)
+	}
+	want := []int{}
+	for tick := fired[0]; tick <= 20; tick = tick + 5 {
+		want = append(want, tick)
+	}
+	if !reflect.DeepEqual(fired, want) {
+		t.Errorf("fired on ticks %v, want %v", fired, want)
+	}
+
+	e.Shot(e.Type.Hitpoints, &Sprite{})
+	for i := 0; i < 5; i++ {
+		e.Update(1000, 1000, nil, geom.Vector{}, nil, nil)
+	}
+	if e.Fire(geom.Vector{X: 100}, geom.Vector{}, arrows) {
+		t.Errorf("a dead archer fired")
+	}
+}
@@ -0,0 +1,202 @@
+package sprites
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
+)
+
+//go:embed enemies.json
+var defaultEnemyTypes []byte
+
+// HitboxDef is how a hitbox is written in an enemy type file. Shape is one
+// of circle, rect or capsule.
+type HitboxDef struct {
+	Shape      string      `json:"shape"`
+	Radius     float64     `json:"radius"`
+	HalfWidth  float64     `json:"halfWidth"`
+	HalfHeight float64     `json:"halfHeight"`
+	HalfLength float64     `json:"halfLength"`
+	Offset     geom.Vector `json:"offset"`
+}
+
+func (d HitboxDef) hitbox() (geom.Hitbox, error) {
+	var h geom.Hitbox
+	switch d.Shape {
+	case "circle":
+		h = geom.CircleHitbox(d.Radius)
+	case "rect":
+		h = geom.RectHitbox(d.HalfWidth, d.HalfHeight)
+	case "capsule":
+		h = geom.CapsuleHitbox(d.HalfLength, d.Radius)
+	default:
+		return h, fmt.Errorf("unknown hitbox shape %q", d.Shape)
+	}
+	return h.WithOffset(d.Offset.X, d.Offset.Y), nil
+}
+
+// EnemyType describes a kind of monster. The sprite sheet holds frames of
+// FrameWidth side by side: WalkFrames of walking followed by the DeadFrame.
+// Each step an enemy moves up to Speed pixels, as its Behavior decides,
+// with a chance of MoveChance, or every step when MoveChance is left out.
+// Enemies with more than Hitpoints grow by ScaleStep for every extra
+// Hitpoints they have. Every hit scores Score, and the dead body stays for
+// DeathTicks before it is buried.
+//
+// Types with FireTicks shoot a Projectile every FireTicks at a hero within
+// ProjectileRange. AimLead is how far ahead of a moving hero they aim, from
+// 0 for straight at the hero to 1 for exactly where the shot will meet it.
+type EnemyType struct {
+	Name          string    `json:"name"`
+	Image         string    `json:"image"`
+	FrameWidth    int       `json:"frameWidth"`
+	WalkFrames    int       `json:"walkFrames"`
+	DeadFrame     int       `json:"deadFrame"`
+	TicksPerFrame int64     `json:"ticksPerFrame"`
+	Hitbox        HitboxDef `json:"hitbox"`
+	Hitpoints     int       `json:"hitpoints"`
+	Speed         float64   `json:"speed"`
+	MoveChance    float64   `json:"moveChance"`
+	ScaleStep     float64   `json:"scaleStep"`
+	Score         int64     `json:"score"`
+	Behavior      string    `json:"behavior"`
+	DeathTicks    int64     `json:"deathTicks"`
+
+	FireTicks       int64   `json:"fireTicks"`
+	Projectile      string  `json:"projectile"`
+	ProjectileSpeed float64 `json:"projectileSpeed"`
+	ProjectileRange float64 `json:"projectileRange"`
+	AimLead         float64 `json:"aimLead"`
+
+	hitbox     geom.Hitbox
+	behavior   EnemyBehavior
+	projectile ArrowKind
+}
+
+var projectiles = map[string]ArrowKind{
+	"arrow":     PlainArrow,
+	"spread":    SpreadArrow,
+	"piercing":  PiercingArrow,
+	"explosive": ExplosiveArrow,
+}
+
+// IsRanged reports whether enemies of the type shoot.
+func (t *EnemyType) IsRanged() bool {
+	return t.FireTicks > 0
+}
+
+// EnemyTypes holds enemy types by name.
+type EnemyTypes map[string]*EnemyType
+
+func (t *EnemyType) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("enemy type without a name")
+	}
+	if !images.Has(t.Image) {
+		return fmt.Errorf("enemy type %s: unknown image %q", t.Name, t.Image)
+	}
+	imageWidth, _ := images.Size(t.Image)
+	if t.FrameWidth <= 0 || t.FrameWidth > imageWidth {
+		return fmt.Errorf("enemy type %s: frame width %d does not fit image %s", t.Name, t.FrameWidth, t.Image)
+	}
+	if t.WalkFrames <= 0 || (t.WalkFrames*t.FrameWidth) > imageWidth || ((t.DeadFrame+1)*t.FrameWidth) > imageWidth {
+		return fmt.Errorf("enemy type %s: frames do not fit image %s", t.Name, t.Image)
+	}
+	if t.TicksPerFrame <= 0 {
+		return fmt.Errorf("enemy type %s: ticksPerFrame must be positive", t.Name)
+	}
+	if t.Hitpoints <= 0 {
+		return fmt.Errorf("enemy type %s: hitpoints must be positive", t.Name)
+	}
+	if t.Speed <= 0 {
+		return fmt.Errorf("enemy type %s: speed must be positive", t.Name)
+	}
+	if t.MoveChance == 0 {
+		t.MoveChance = 1
+	}
+	if t.MoveChance < 0 || t.MoveChance > 1 {
+		return fmt.Errorf("enemy type %s: moveChance must be more than 0 and at most 1", t.Name)
+	}
+	if t.Behavior == "" {
+		t.Behavior = "chaser"
+	}
+	behavior, ok := Behavior(t.Behavior)
+	if !ok {
+		return fmt.Errorf("enemy type %s: unknown behavior %q", t.Name, t.Behavior)
+	}
+	t.behavior = behavior
+	if t.FireTicks > 0 {
+		if t.Projectile == "" {
+			t.Projectile = "arrow"
+		}
+		projectile, ok := projectiles[t.Projectile]
+		if !ok {
+			return fmt.Errorf("enemy type %s: unknown projectile %q", t.Name, t.Projectile)
+		}
+		t.projectile = projectile
+		if t.ProjectileSpeed <= 0 || t.ProjectileRange <= 0 {
+			return fmt.Errorf("enemy type %s: projectileSpeed and projectileRange must be positive", t.Name)
+		}
+	}
+	hitbox, err := t.Hitbox.hitbox()
+	if err != nil {
+		return fmt.Errorf("enemy type %s: %w", t.Name, err)
+	}
+	t.hitbox = hitbox
+	return nil
+}
+
+// Load reads a JSON list of enemy types from r and adds them to types,
+// replacing any type of the same name. Images that are not built into the
+// game are read from files relative to dir.
+func (types EnemyTypes) Load(r io.Reader, dir string) error {
+	loaded := []*EnemyType{}
+	if err := json.NewDecoder(r).Decode(&loaded); err != nil {
+		return fmt.Errorf("enemy types: %w", err)
+	}
+	for _, t := range loaded {
+		if t.Image != "" && !images.Has(t.Image) && dir != "" {
+			path := filepath.Join(dir, t.Image)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("enemy type %s: %w", t.Name, err)
+			}
+			if err := images.Add(path, data); err != nil {
+				return err
+			}
+			t.Image = path
+		}
+		if err := t.validate(); err != nil {
+			return err
+		}
+		types[t.Name] = t
+	}
+	return nil
+}
+
+// LoadFile adds the enemy types in the JSON file at path to types.
+func (types EnemyTypes) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return types.Load(f, filepath.Dir(path))
+}
+
+// DefaultEnemyTypes returns the enemy types built into the game.
+func DefaultEnemyTypes() EnemyTypes {
+	types := EnemyTypes{}
+	if err := types.Load(bytes.NewReader(defaultEnemyTypes), ""); err != nil {
+		log.Fatal(err)
+	}
+	return types
+}
@@ -0,0 +1,109 @@
+package sprites
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// goodEnemy returns the fields of a valid enemy type.
+func goodEnemy() map[string]interface{} {
+	return map[string]interface{}{
+		"name":          "imp",
+		"image":         "goblin",
+		"frameWidth":    32,
+		"walkFrames":    3,
+		"deadFrame":     3,
+		"ticksPerFrame": 60,
+		"hitbox":        map[string]interface{}{"shape": "circle", "radius": 8},
+		"hitpoints":     50,
+		"speed":         1.5,
+		"moveChance":    0.5,
+	}
+}
+
+// loadEnemy loads an enemy type made from goodEnemy with change applied,
+// setting fields to the values given and leaving out those set to nil.
+func loadEnemy(t *testing.T, change map[string]interface{}) (*EnemyType, error) {
+	t.Helper()
+	fields := goodEnemy()
+	for k, v := range change {
+		if v == nil {
+			delete(fields, k)
+		} else {
+			fields[k] = v
+		}
+	}
+	data, err := json.Marshal([]interface{}{fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := EnemyTypes{}
+	if err := types.Load(bytes.NewReader(data), ""); err != nil {
+		return nil, err
+	}
+	return types[fields["name"].(string)], nil
+}
+
+func TestLoadEnemyTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		change map[string]interface{}
+		check  func(*EnemyType) bool
+	}{
+		{"as written", nil, func(e *EnemyType) bool {
+			return e.Speed == 1.5 && e.MoveChance == 0.5 && e.Behavior == "chaser" && !e.IsRanged()
+		}},
+		{"no moveChance", map[string]interface{}{"moveChance": nil}, func(e *EnemyType) bool { return e.MoveChance == 1 }},
+		{"always moves", map[string]interface{}{"moveChance": 1}, func(e *EnemyType) bool { return e.MoveChance == 1 }},
+		{"behavior", map[string]interface{}{"behavior": "coward"}, func(e *EnemyType) bool {
+			_, ok := e.behavior.(Coward)
+			return ok
+		}},
+		{"ranged", map[string]interface{}{"fireTicks": 30, "projectileSpeed": 4, "projectileRange": 300}, func(e *EnemyType) bool {
+			return e.IsRanged() && e.projectile == PlainArrow
+		}},
+		{"ranged with a projectile", map[string]interface{}{"fireTicks": 30, "projectile": "piercing", "projectileSpeed": 4, "projectileRange": 300}, func(e *EnemyType) bool {
+			return e.projectile == PiercingArrow
+		}},
+	}
+	for _, tt := range tests {
+		e, err := loadEnemy(t, tt.change)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if !tt.check(e) {
+			t.Errorf("%s: loaded %+v", tt.name, e)
+		}
+	}
+}
+
+func TestLoadEnemyTypeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change map[string]interface{}
+		want   string
+	}{
+		{"no name", map[string]interface{}{"name": ""}, "enemy type without a name"},
+		{"unknown image", map[string]interface{}{"image": "dragon"}, `unknown image "dragon"`},
+		{"no frame width", map[string]interface{}{"frameWidth": nil}, "frame width 0"},
+		{"too many frames", map[string]interface{}{"walkFrames": 30}, "frames do not fit"},
+		{"no ticksPerFrame", map[string]interface{}{"ticksPerFrame": nil}, "ticksPerFrame must be positive"},
+		{"no hitpoints", map[string]interface{}{"hitpoints": nil}, "hitpoints must be positive"},
+		{"no speed", map[string]interface{}{"speed": nil}, "speed must be positive"},
+		{"backwards", map[string]interface{}{"speed": -1}, "speed must be positive"},
+		{"negative moveChance", map[string]interface{}{"moveChance": -0.5}, "moveChance must be"},
+		{"moveChance over 1", map[string]interface{}{"moveChance": 2}, "moveChance must be"},
+		{"unknown behavior", map[string]interface{}{"behavior": "dancer"}, `unknown behavior "dancer"`},
+		{"unknown projectile", map[string]interface{}{"fireTicks": 30, "projectile": "rock", "projectileSpeed": 4, "projectileRange": 300}, `unknown projectile "rock"`},
+		{"no projectile range", map[string]interface{}{"fireTicks": 30, "projectileSpeed": 4}, "projectileRange must be positive"},
+		{"no hitbox", map[string]interface{}{"hitbox": nil}, "unknown hitbox shape"},
+		{"bad json", map[string]interface{}{"speed": "fast"}, "enemy types:"},
+	}
+	for _, tt := range tests {
+		_, err := loadEnemy(t, tt.change)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
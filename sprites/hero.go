@@ -1,87 +1,78 @@
 package sprites
 
 import (
+	"image/color"
 	"math"
 
-	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
+	"github.com/markrzasa/arrowsaway/input"
+	"github.com/markrzasa/arrowsaway/tilemap"
 )
 
 type Hero struct {
 	Sprite *Sprite
+	Weapon *Weapon
+
+	// Velocity is how far the hero moved during the last update
+	Velocity geom.Vector
 }
 
-func NewHero(image *ebiten.Image) *Hero {
+const (
+	// heroRadius is how far the hero's hitbox reaches from its middle
+	heroRadius float64 = 12
+)
+
+const (
+	HeroStanding int = iota
+	HeroWinner
+	HeroGameOver
+)
+
+func NewHero(image string, tint color.Color) *Hero {
+	imageWidth, _ := images.Size(image)
 	h := Hero{
-		Sprite: NewSprite(image.Bounds().Dx() / 3, image),
+		Sprite: NewSprite(imageWidth/3, image),
+		Weapon: NewWeapon(Bow),
 	}
+	h.Sprite.Tint = tint
+	h.Sprite.SetHitbox(geom.CircleHitbox(heroRadius))
 	return &h
 }
 
-func (h *Hero) Update(gamepadIds *map[ebiten.GamepadID]bool, height, width int) {
-	for id := range *gamepadIds {
-		prevX := h.Sprite.X
-		prevY := h.Sprite.Y
-		x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
-		x = math.Round(x*10) / 10
-		y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
-		y = math.Round(y*10) / 10
-		h.Sprite.X = h.Sprite.X + int(x*10)
-		if h.Sprite.X < (h.Sprite.imageWidth / 2) {
-			h.Sprite.X = h.Sprite.imageWidth / 2
-		} else if h.Sprite.X > (width - (h.Sprite.imageWidth / 2)) {
-			h.Sprite.X = width - (h.Sprite.imageWidth / 2)
-		}
-		h.Sprite.Y = h.Sprite.Y + int(y*10)
-		if h.Sprite.Y < (h.Sprite.image.Bounds().Dy() / 2) {
-			h.Sprite.Y = h.Sprite.image.Bounds().Dy() / 2
-		} else if h.Sprite.Y > (height - (h.Sprite.image.Bounds().Dy() / 2)) {
-			h.Sprite.Y = height - (h.Sprite.image.Bounds().Dy() / 2)
-		}
-
-		rightX := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickHorizontal)
-		rightX = math.Round(rightX*10) / 10
-		rightY := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickVertical)
-		rightY = math.Round(rightY*10) / 10
-		if rightX == 0 && rightY == 0 {
-			h.Sprite.Radians = 0
-		} else {
-			rightX = math.Round(rightX*10) / 10
-			rightX = float64(h.Sprite.X) + (rightX * 10)
-			if int(rightX) < (h.Sprite.imageWidth / 2) {
-				rightX = float64(h.Sprite.imageWidth) / 2
-			} else if h.Sprite.X > (width - (h.Sprite.imageWidth / 2)) {
-				rightX = float64(width - (h.Sprite.imageWidth / 2))
-			}
-	
-			rightY = math.Round(rightY*10) / 10
-			rightY = float64(h.Sprite.Y + int(rightY*10))
-			if int(rightY) < (h.Sprite.image.Bounds().Dy() / 2) {
-				rightY = float64(h.Sprite.image.Bounds().Dy() / 2)
-			} else if h.Sprite.Y > (height - (h.Sprite.image.Bounds().Dy() / 2)) {
-				rightY = float64(height - (h.Sprite.image.Bounds().Dy() / 2))
-			}
-	
-			deltaX := int(rightX) - prevX
-			deltaY := int(rightY) - prevY
-			h.Sprite.Radians = math.Atan2(float64(deltaY), float64(deltaX)) - (math.Pi / 180)	
-		}
+func (h *Hero) move(move geom.Vector, height, width int, walls *tilemap.Map) {
+	delta := geom.Vector{X: float64(int(move.X * 10)), Y: float64(int(move.Y * 10))}
+	to := walls.Move(h.Position(), delta, heroRadius)
+	h.Sprite.X = int(to.X)
+	if h.Sprite.X < (h.Sprite.imageWidth / 2) {
+		h.Sprite.X = h.Sprite.imageWidth / 2
+	} else if h.Sprite.X > (width - (h.Sprite.imageWidth / 2)) {
+		h.Sprite.X = width - (h.Sprite.imageWidth / 2)
+	}
+	h.Sprite.Y = int(to.Y)
+	if h.Sprite.Y < (h.Sprite.imageHeight / 2) {
+		h.Sprite.Y = h.Sprite.imageHeight / 2
+	} else if h.Sprite.Y > (height - (h.Sprite.imageHeight / 2)) {
+		h.Sprite.Y = height - (h.Sprite.imageHeight / 2)
 	}
 }
 
-func (h *Hero) Draw(screen *ebiten.Image) {
-	h.Sprite.Draw(screen, 0)
+func (h *Hero) aim(aim geom.Vector) {
+	if aim.IsZero() {
+		h.Sprite.Radians = 0
+	} else {
+		h.Sprite.Radians = math.Atan2(aim.Y, aim.X) - (math.Pi / 180)
+	}
 }
 
-func (h *Hero) Winner(screen *ebiten.Image, width, height int) {
-	h.Sprite.Scale(10)
-	h.Sprite.X = width / 2
-	h.Sprite.Y = height / 2
-	h.Sprite.Draw(screen, 1)
+func (h *Hero) Position() geom.Vector {
+	return geom.Vector{X: float64(h.Sprite.X), Y: float64(h.Sprite.Y)}
 }
 
-func (h *Hero) GameOver(screen *ebiten.Image, width, height int) {
-	h.Sprite.Scale(10)
-	h.Sprite.X = width / 2
-	h.Sprite.Y = height / 2
-	h.Sprite.Draw(screen, 2)
+// Update moves and aims the hero as intent asks, keeping it out of walls.
+func (h *Hero) Update(intent input.Intent, height, width int, walls *tilemap.Map) {
+	x, y := h.Sprite.X, h.Sprite.Y
+	h.move(intent.Move, height, width, walls)
+	h.Velocity = geom.Vector{X: float64(h.Sprite.X - x), Y: float64(h.Sprite.Y - y)}
+	h.aim(intent.Aim)
 }
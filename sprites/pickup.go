@@ -0,0 +1,26 @@
+package sprites
+
+import (
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
+)
+
+// Pickup is a weapon lying on the ground waiting for a hero to walk over it.
+type Pickup struct {
+	Weapon WeaponKind
+	Sprite *Sprite
+}
+
+func NewPickup(x, y int, weapon WeaponKind) *Pickup {
+	image := arrowImage(NewWeapon(weapon).Arrow)
+	imageWidth, _ := images.Size(image)
+	pickup := &Pickup{
+		Weapon: weapon,
+		Sprite: NewSprite(imageWidth, image),
+	}
+	pickup.Sprite.X = x
+	pickup.Sprite.Y = y
+	pickup.Sprite.Scale(2)
+	pickup.Sprite.SetHitbox(geom.CircleHitbox(float64(imageWidth) / 2))
+	return pickup
+}
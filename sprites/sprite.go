@@ -2,76 +2,78 @@ package sprites
 
 import (
 	"image"
+	"image/color"
 
-	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/markrzasa/arrowsaway/geom"
+	"github.com/markrzasa/arrowsaway/images"
 )
 
 type Sprite struct {
-	X, Y, imageWidth int
+	X, Y, imageWidth, imageHeight int
 
-	image *ebiten.Image
+	Image string
+	Frame int
 
 	Radians, ScaleX, ScaleY float64
+
+	Tint color.Color
+
+	Hitbox    geom.Hitbox
+	hasHitbox bool
 }
 
-func NewSprite(imageWidth int, image *ebiten.Image) *Sprite {
-	return &Sprite{
-		X:          0,
-		Y:          0,
-		ScaleX:     1,
-		ScaleY:     1,
-		imageWidth: imageWidth,
-		image:      image,
-		Radians:    0,
+func NewSprite(imageWidth int, image string) *Sprite {
+	s := &Sprite{}
+	s.reset(imageWidth, image)
+	return s
+}
+
+// reset makes s look like a new sprite so pooled sprites can be reused.
+func (s *Sprite) reset(imageWidth int, image string) {
+	_, imageHeight := images.Size(image)
+	*s = Sprite{
+		X:           0,
+		Y:           0,
+		ScaleX:      1,
+		ScaleY:      1,
+		imageWidth:  imageWidth,
+		imageHeight: imageHeight,
+		Image:       image,
+		Frame:       0,
+		Radians:     0,
 	}
 }
 
-func (s *Sprite) Scale(scale float64) {
-	s.ScaleX = scale
-	s.ScaleY = scale
+func (s *Sprite) FrameWidth() int {
+	return s.imageWidth
 }
 
-func (s *Sprite) Draw(screen *ebiten.Image, frame int) {
-	bounds := s.Bounds()
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(-float64(bounds.Dx()) / 2, -float64(bounds.Dy()) / 2)
-	op.GeoM.Rotate(s.Radians)
-	op.GeoM.Scale(s.ScaleX, float64(s.ScaleY))
-	op.GeoM.Translate(float64(s.X), float64(s.Y))
-	subImageRect := image.Rect(frame*s.imageWidth, 0, (frame+1)*s.imageWidth, s.image.Bounds().Dy())
-	subImage := s.image.SubImage(subImageRect).(*ebiten.Image)
-	screen.DrawImage(subImage, op)
+func (s *Sprite) FrameHeight() int {
+	return s.imageHeight
 }
 
-func (s *Sprite) DrawSubImage(screen *ebiten.Image, subImageWidth int) {
-	bounds := s.Bounds()
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(-float64(subImageWidth) / 2, -float64(bounds.Dy()) / 2)
-	op.GeoM.Rotate(s.Radians)
-	op.GeoM.Scale(s.ScaleX, float64(s.ScaleY))
-	op.GeoM.Translate(float64(s.X), float64(s.Y))
-	subImageRect := image.Rect(0, 0, subImageWidth, s.image.Bounds().Dy())
-	subImage := s.image.SubImage(subImageRect).(*ebiten.Image)
-	screen.DrawImage(subImage, op)
+func (s *Sprite) Scale(scale float64) {
+	s.ScaleX = scale
+	s.ScaleY = scale
 }
 
-func (s *Sprite) Bounds() *image.Rectangle {
-	return &image.Rectangle{
+func (s *Sprite) Bounds() image.Rectangle {
+	return image.Rectangle{
 		Min: image.Point{
 			X: s.X - (s.imageWidth / 2),
-			Y: s.Y - (s.image.Bounds().Dy() / 2),
+			Y: s.Y - (s.imageHeight / 2),
 		},
 		Max: image.Point{
 			X: s.X + (s.imageWidth / 2),
-			Y: s.Y + (s.image.Bounds().Dy() / 2),
+			Y: s.Y + (s.imageHeight / 2),
 		},
 	}
 }
 
-func (s *Sprite) ScaledBounds() *image.Rectangle {
+func (s *Sprite) ScaledBounds() image.Rectangle {
 	scaledWidth := float64(s.imageWidth) * s.ScaleX
-	scaledHeight := float64(s.image.Bounds().Dy()) * s.ScaleY
-	return &image.Rectangle{
+	scaledHeight := float64(s.imageHeight) * s.ScaleY
+	return image.Rectangle{
 		Min: image.Point{
 			X: int(float64(s.X) - (scaledWidth / 2)),
 			Y: int(float64(s.Y) - (scaledHeight / 2)),
@@ -83,8 +85,27 @@ func (s *Sprite) ScaledBounds() *image.Rectangle {
 	}
 }
 
+func (s *Sprite) SetHitbox(hitbox geom.Hitbox) {
+	s.Hitbox = hitbox
+	s.hasHitbox = true
+}
+
+// Shape returns the sprite's hitbox placed where the sprite is drawn. A
+// sprite without a hitbox collides with its whole frame.
+func (s *Sprite) Shape() geom.Shape {
+	hitbox := geom.RectHitbox(float64(s.imageWidth)/2, float64(s.imageHeight)/2)
+	if s.hasHitbox {
+		hitbox = s.Hitbox
+	}
+	return hitbox.Place(geom.Vector{X: float64(s.X), Y: float64(s.Y)}, s.Radians, s.ScaleX, s.ScaleY)
+}
+
+func (s *Sprite) Rect() geom.Rect {
+	return s.Shape().Bounds()
+}
+
 func (s *Sprite) Intersect(o *Sprite) bool {
-	return s.ScaledBounds().Intersect(*o.ScaledBounds()) != image.Rectangle{}
+	return geom.Overlap(s.Shape(), o.Shape())
 }
 
 func (s *Sprite) Center(width, height int) {
@@ -0,0 +1,180 @@
+package sprites
+
+import (
+	"math/rand"
+
+	"github.com/markrzasa/arrowsaway/entity"
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// EnemyStore keeps enemies in the order they were added. Removed enemies are
+// pooled and handed out again by Spawn, so a store that has warmed up does
+// not allocate.
+type EnemyStore struct {
+	ids     *entity.Store
+	enemies []*Enemy
+	all     []*Enemy
+	free    []*Enemy
+}
+
+func NewEnemyStore() *EnemyStore {
+	return &EnemyStore{ids: entity.NewStore()}
+}
+
+func (s *EnemyStore) Add(e *Enemy) entity.ID {
+	id := s.ids.Add()
+	if id.Slot() == len(s.enemies) {
+		s.enemies = append(s.enemies, e)
+	} else {
+		s.enemies[id.Slot()] = e
+	}
+	return id
+}
+
+// Spawn adds an enemy set up the way NewEnemy would, reusing a removed one
+// when there is one.
+func (s *EnemyStore) Spawn(t *EnemyType, x, y, hp int, rng *rand.Rand) *Enemy {
+	n := len(s.free)
+	if n == 0 {
+		e := NewEnemy(t, x, y, hp, rng)
+		s.Add(e)
+		return e
+	}
+	e := s.free[n-1]
+	s.free = s.free[:n-1]
+	e.reset(t, x, y, hp, rng)
+	s.Add(e)
+	return e
+}
+
+// SpawnBoss adds a boss of type t at x, y.
+func (s *EnemyStore) SpawnBoss(t *BossType, x, y int, rng *rand.Rand) *Enemy {
+	e := s.Spawn(t.enemy, x, y, t.Hitpoints, rng)
+	e.makeBoss(t)
+	return e
+}
+
+// Get returns the enemy id refers to, or nil once it has been removed.
+func (s *EnemyStore) Get(id entity.ID) *Enemy {
+	if !s.ids.Contains(id) {
+		return nil
+	}
+	return s.enemies[id.Slot()]
+}
+
+// Remove takes the enemy out of the store. It must not be used afterwards
+// since Spawn may hand it out again.
+func (s *EnemyStore) Remove(id entity.ID) {
+	if s.ids.Remove(id) {
+		s.free = append(s.free, s.enemies[id.Slot()])
+		s.enemies[id.Slot()] = nil
+	}
+}
+
+func (s *EnemyStore) Len() int {
+	return s.ids.Len()
+}
+
+func (s *EnemyStore) IDs() []entity.ID {
+	return s.ids.IDs()
+}
+
+// All returns the enemies oldest first. The slice is reused by the next call.
+func (s *EnemyStore) All() []*Enemy {
+	s.all = s.all[:0]
+	for _, id := range s.ids.IDs() {
+		s.all = append(s.all, s.enemies[id.Slot()])
+	}
+	return s.all
+}
+
+func (s *EnemyStore) Clear() {
+	s.free = append(s.free, s.All()...)
+	s.ids.Clear()
+	for i := range s.enemies {
+		s.enemies[i] = nil
+	}
+	s.enemies = s.enemies[:0]
+}
+
+// ArrowStore keeps arrows in the order they were fired, pooling removed
+// arrows the same way EnemyStore does.
+type ArrowStore struct {
+	ids    *entity.Store
+	arrows []*Arrow
+	all    []*Arrow
+	free   []*Arrow
+}
+
+func NewArrowStore() *ArrowStore {
+	return &ArrowStore{ids: entity.NewStore()}
+}
+
+func (s *ArrowStore) Add(a *Arrow) entity.ID {
+	id := s.ids.Add()
+	if id.Slot() == len(s.arrows) {
+		s.arrows = append(s.arrows, a)
+	} else {
+		s.arrows[id.Slot()] = a
+	}
+	return id
+}
+
+// Spawn adds an arrow set up the way NewArrow would, reusing a removed one
+// when there is one.
+func (s *ArrowStore) Spawn(owner int, kind ArrowKind, start, direction geom.Vector, speed, maxRange float64) *Arrow {
+	n := len(s.free)
+	if n == 0 {
+		a := NewArrow(owner, kind, start, direction, speed, maxRange)
+		s.Add(a)
+		return a
+	}
+	a := s.free[n-1]
+	s.free = s.free[:n-1]
+	a.reset(owner, kind, start, direction, speed, maxRange)
+	s.Add(a)
+	return a
+}
+
+// Get returns the arrow id refers to, or nil once it has been removed.
+func (s *ArrowStore) Get(id entity.ID) *Arrow {
+	if !s.ids.Contains(id) {
+		return nil
+	}
+	return s.arrows[id.Slot()]
+}
+
+// Remove takes the arrow out of the store. It must not be used afterwards
+// since Spawn may hand it out again.
+func (s *ArrowStore) Remove(id entity.ID) {
+	if s.ids.Remove(id) {
+		s.free = append(s.free, s.arrows[id.Slot()])
+		s.arrows[id.Slot()] = nil
+	}
+}
+
+func (s *ArrowStore) Len() int {
+	return s.ids.Len()
+}
+
+func (s *ArrowStore) IDs() []entity.ID {
+	return s.ids.IDs()
+}
+
+// All returns the arrows oldest first. The slice is reused by the next call.
+func (s *ArrowStore) All() []*Arrow {
+	s.all = s.all[:0]
+	for _, id := range s.ids.IDs() {
+		s.all = append(s.all, s.arrows[id.Slot()])
+	}
+	return s.all
+}
+
+func (s *ArrowStore) Clear() {
+	s.free = append(s.free, s.All()...)
+	s.ids.Clear()
+	for i := range s.arrows {
+		s.arrows[i] = nil
+	}
+	s.arrows = s.arrows[:0]
+}
@@ -0,0 +1,125 @@
+package sprites
+
+import (
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+type WeaponKind int
+
+const (
+	Bow WeaponKind = iota
+	SpreadBow
+	FanBow
+	PiercingBow
+	ExplosiveBow
+	NumWeaponKinds
+)
+
+// Weapon decides when its owner may shoot and what the shot looks like.
+// Spread is the angle in radians between the first and last projectile of a
+// multi projectile shot.
+type Weapon struct {
+	Kind            WeaponKind
+	Name            string
+	Arrow           ArrowKind
+	CooldownTicks   int64
+	ProjectileSpeed float64
+	Range           float64
+	Damage          int
+	Projectiles     int
+	Spread          float64
+	Pierce          int
+	BlastRadius     float64
+
+	lastShotTick int64
+	fired        bool
+}
+
+func NewWeapon(kind WeaponKind) *Weapon {
+	w := &Weapon{
+		Kind:            Bow,
+		Name:            "Bow",
+		Arrow:           PlainArrow,
+		CooldownTicks:   15,
+		ProjectileSpeed: DefaultArrowSpeed,
+		Range:           DefaultArrowRange,
+		Damage:          1,
+		Projectiles:     1,
+		Spread:          0,
+		Pierce:          0,
+		BlastRadius:     0,
+	}
+	switch kind {
+	case SpreadBow:
+		w.Kind = SpreadBow
+		w.Name = "Spread Bow"
+		w.Arrow = SpreadArrow
+		w.CooldownTicks = 21
+		w.Projectiles = 3
+		w.Range = 600
+		w.Spread = 30 * math.Pi / 180
+	case FanBow:
+		w.Kind = FanBow
+		w.Name = "Fan Bow"
+		w.Arrow = SpreadArrow
+		w.CooldownTicks = 27
+		w.Projectiles = 5
+		w.Range = 450
+		w.Spread = 60 * math.Pi / 180
+	case PiercingBow:
+		w.Kind = PiercingBow
+		w.Name = "Piercing Bow"
+		w.Arrow = PiercingArrow
+		w.CooldownTicks = 18
+		w.ProjectileSpeed = 14
+		w.Range = 1200
+		w.Pierce = 3
+	case ExplosiveBow:
+		w.Kind = ExplosiveBow
+		w.Name = "Explosive Bow"
+		w.Arrow = ExplosiveArrow
+		w.CooldownTicks = 36
+		w.ProjectileSpeed = 8
+		w.Range = 700
+		w.Damage = 2
+		w.BlastRadius = 60
+	}
+	return w
+}
+
+func (w *Weapon) CanFire(tick int64) bool {
+	return !w.fired || tick >= (w.lastShotTick+w.CooldownTicks)
+}
+
+// direction returns the direction of projectile i of a shot toward aim.
+func (w *Weapon) direction(aim geom.Vector, i int) geom.Vector {
+	center := aim.Angle()
+	if w.Projectiles <= 1 {
+		return geom.FromAngle(center)
+	}
+	step := w.Spread / float64(w.Projectiles-1)
+	return geom.FromAngle(center - (w.Spread / 2) + (step * float64(i)))
+}
+
+// Fire shoots toward aim from origin, adding the arrows to arrows, and starts
+// the cooldown. Callers should check CanFire first.
+func (w *Weapon) Fire(owner int, origin, aim geom.Vector, tick int64, arrows *ArrowStore) {
+	if aim.IsZero() {
+		return
+	}
+	w.fired = true
+	w.lastShotTick = tick
+
+	projectiles := w.Projectiles
+	if projectiles < 1 {
+		projectiles = 1
+	}
+	for i := 0; i < projectiles; i++ {
+		arrow := arrows.Spawn(owner, w.Arrow, origin, w.direction(aim, i), w.ProjectileSpeed, w.Range)
+		arrow.Damage = w.Damage
+		arrow.Pierce = w.Pierce
+		arrow.BlastRadius = w.BlastRadius
+	}
+}
@@ -0,0 +1,161 @@
+package tilemap
+
+import (
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// neighbors are the steps to the tiles around a tile, straight steps first.
+var neighbors = [8][2]int{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+}
+
+type node struct {
+	cell int
+	dist float64
+}
+
+// Field is a flow field over a map. For every floor tile it knows how far
+// it is to the nearest goal walking around the solid tiles, so any number of
+// enemies can find their way after a single search. The zero Field is ready
+// to use and keeps its buffers from one Update to the next.
+type Field struct {
+	m     *Map
+	dist  []float64
+	queue []node
+}
+
+// Update searches m outward from the tiles the goals are in. Goals off the
+// map are left out.
+func (f *Field) Update(m *Map, goals []geom.Vector) {
+	f.m = m
+	if m == nil {
+		return
+	}
+	n := m.cols * m.rows
+	if cap(f.dist) < n {
+		f.dist = make([]float64, n)
+	}
+	f.dist = f.dist[:n]
+	for i := range f.dist {
+		f.dist[i] = math.Inf(1)
+	}
+	f.queue = f.queue[:0]
+	for _, g := range goals {
+		col, row := m.Cell(g)
+		if !m.inside(col, row) || m.At(col, row).Solid() {
+			continue
+		}
+		f.dist[(row*m.cols)+col] = 0
+		f.push(node{cell: (row * m.cols) + col})
+	}
+
+	for len(f.queue) > 0 {
+		current := f.pop()
+		if current.dist > f.dist[current.cell] {
+			continue
+		}
+		col, row := current.cell%m.cols, current.cell/m.cols
+		for _, step := range neighbors {
+			next, cost, ok := m.step(col, row, step)
+			if !ok {
+				continue
+			}
+			d := current.dist + cost
+			if d < f.dist[next] {
+				f.dist[next] = d
+				f.push(node{cell: next, dist: d})
+			}
+		}
+	}
+}
+
+func (m *Map) inside(col, row int) bool {
+	return col >= 0 && row >= 0 && col < m.cols && row < m.rows
+}
+
+// step returns the tile a step away from col, row and how far away it is,
+// or false if the step leaves the map, ends in a solid tile or cuts the
+// corner of one.
+func (m *Map) step(col, row int, step [2]int) (int, float64, bool) {
+	c, r := col+step[0], row+step[1]
+	if !m.inside(c, r) || m.At(c, r).Solid() {
+		return 0, 0, false
+	}
+	if step[0] != 0 && step[1] != 0 {
+		if m.At(c, row).Solid() || m.At(col, r).Solid() {
+			return 0, 0, false
+		}
+		return (r * m.cols) + c, math.Sqrt2 * m.size, true
+	}
+	return (r * m.cols) + c, m.size, true
+}
+
+// Toward returns which way to walk from p to reach the nearest goal and how
+// far it is along the way. It returns false when the field cannot help: p
+// is in a goal's tile, off the map, in a solid tile or cut off from every
+// goal.
+func (f *Field) Toward(p geom.Vector) (geom.Vector, float64, bool) {
+	m := f.m
+	if m == nil {
+		return geom.Vector{}, 0, false
+	}
+	col, row := m.Cell(p)
+	if !m.inside(col, row) {
+		return geom.Vector{}, 0, false
+	}
+	here := f.dist[(row*m.cols)+col]
+	if here == 0 || math.IsInf(here, 1) {
+		return geom.Vector{}, 0, false
+	}
+	best, bestDist := -1, here
+	for _, step := range neighbors {
+		next, _, ok := m.step(col, row, step)
+		if ok && f.dist[next] < bestDist {
+			best = next
+			bestDist = f.dist[next]
+		}
+	}
+	if best < 0 {
+		return geom.Vector{}, 0, false
+	}
+	delta := m.Center(best%m.cols, best/m.cols).Sub(p)
+	return delta.Normalize(), bestDist + delta.Length(), true
+}
+
+// push and pop keep the queue a binary heap with the nearest node first.
+func (f *Field) push(n node) {
+	f.queue = append(f.queue, n)
+	i := len(f.queue) - 1
+	for i > 0 {
+		parent := (i - 1) / 2
+		if f.queue[parent].dist <= f.queue[i].dist {
+			break
+		}
+		f.queue[parent], f.queue[i] = f.queue[i], f.queue[parent]
+		i = parent
+	}
+}
+
+func (f *Field) pop() node {
+	top := f.queue[0]
+	last := len(f.queue) - 1
+	f.queue[0] = f.queue[last]
+	f.queue = f.queue[:last]
+	i := 0
+	for {
+		smallest := i
+		for _, child := range [2]int{(2 * i) + 1, (2 * i) + 2} {
+			if child < last && f.queue[child].dist < f.queue[smallest].dist {
+				smallest = child
+			}
+		}
+		if smallest == i {
+			return top
+		}
+		f.queue[i], f.queue[smallest] = f.queue[smallest], f.queue[i]
+		i = smallest
+	}
+}
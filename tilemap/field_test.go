@@ -0,0 +1,90 @@
+package tilemap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+func parse(t *testing.T, rows []string) *Map {
+	t.Helper()
+	m, err := Parse(rows, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// cornerRows has a single wall tile for paths to go around.
+var cornerRows = []string{
+	"....",
+	".#..",
+	"....",
+}
+
+func TestFieldDistances(t *testing.T) {
+	m := parse(t, cornerRows)
+	f := Field{}
+	f.Update(m, []geom.Vector{{X: 5, Y: 5}})
+	diagonal := math.Sqrt2 * 10
+	tests := []struct {
+		col, row int
+		want     float64
+	}{
+		{0, 0, 0},
+		{1, 0, 10},
+		{0, 1, 10},
+		{2, 0, 20},
+		{0, 2, 20},
+		{3, 0, 30},
+		{3, 1, 20 + diagonal},
+		{3, 2, 30 + diagonal},
+		{2, 2, 40},
+		// next to the wall, so no cutting its corner from the goal's side
+		{2, 1, 30},
+		{1, 2, 30},
+		{1, 1, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		if got := f.dist[(tt.row*m.cols)+tt.col]; math.Abs(got-tt.want) > 1e-9 && got != tt.want {
+			t.Errorf("distance to %d, %d is %v, want %v", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestFieldToward(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      []string
+		p         geom.Vector
+		direction geom.Vector
+		distance  float64
+		ok        bool
+	}{
+		{"around the wall", cornerRows, geom.Vector{X: 25, Y: 15}, geom.Vector{Y: -1}, 30, true},
+		{"around the other side", cornerRows, geom.Vector{X: 15, Y: 25}, geom.Vector{X: -1}, 30, true},
+		{"from off the middle of a tile", cornerRows, geom.Vector{X: 15, Y: 8}, geom.Vector{X: -10, Y: -3}.Normalize(), math.Hypot(10, 3), true},
+		{"in the goal tile", cornerRows, geom.Vector{X: 2, Y: 8}, geom.Vector{}, 0, false},
+		{"off the map", cornerRows, geom.Vector{X: -5, Y: 5}, geom.Vector{}, 0, false},
+		{"in a wall", cornerRows, geom.Vector{X: 15, Y: 15}, geom.Vector{}, 0, false},
+		{"walled in", []string{"....", "..##", "..#.", "..##"}, geom.Vector{X: 35, Y: 25}, geom.Vector{}, 0, false},
+		{"only a corner away", []string{"..#", "##."}, geom.Vector{X: 25, Y: 15}, geom.Vector{}, 0, false},
+	}
+	for _, tt := range tests {
+		f := Field{}
+		f.Update(parse(t, tt.rows), []geom.Vector{{X: 5, Y: 5}})
+		direction, distance, ok := f.Toward(tt.p)
+		if ok != tt.ok || !near(direction, tt.direction) || math.Abs(distance-tt.distance) > 1e-9 {
+			t.Errorf("%s: Toward = %v, %v, %v, want %v, %v, %v", tt.name, direction, distance, ok, tt.direction, tt.distance, tt.ok)
+		}
+	}
+}
+
+func TestFieldWithoutMap(t *testing.T) {
+	f := Field{}
+	f.Update(nil, []geom.Vector{{X: 5, Y: 5}})
+	if _, _, ok := f.Toward(geom.Vector{X: 50, Y: 50}); ok {
+		t.Errorf("a field without a map found a way")
+	}
+}
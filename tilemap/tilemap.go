@@ -0,0 +1,275 @@
+// Package tilemap lays a grid of floor, wall and rock tiles over the arena
+// and answers the questions movement and arrows ask of it.
+package tilemap
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+type Tile byte
+
+const (
+	Floor Tile = iota
+	Wall
+	Rock
+)
+
+var symbols = map[rune]Tile{
+	'.': Floor,
+	'#': Wall,
+	'o': Rock,
+}
+
+// Solid reports whether nothing can pass through the tile.
+func (t Tile) Solid() bool {
+	return t != Floor
+}
+
+// Map is a grid of square tiles Size pixels across with its top left corner
+// at the top left of the arena. Everything outside the grid is floor. A nil
+// Map is an arena without any walls.
+type Map struct {
+	size       float64
+	cols, rows int
+	tiles      []Tile
+}
+
+// Parse reads a map from rows of text, one character per tile: . for floor,
+// # for wall and o for rock.
+func Parse(rows []string, size int) (*Map, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("map tile size must be positive")
+	}
+	m := &Map{size: float64(size), rows: len(rows)}
+	for r, row := range rows {
+		cols := 0
+		for _, c := range row {
+			t, ok := symbols[c]
+			if !ok {
+				return nil, fmt.Errorf("map row %d: unknown tile %q", r+1, c)
+			}
+			m.tiles = append(m.tiles, t)
+			cols = cols + 1
+		}
+		if r == 0 {
+			m.cols = cols
+		} else if cols != m.cols {
+			return nil, fmt.Errorf("map row %d is %d tiles long, not %d", r+1, cols, m.cols)
+		}
+	}
+	return m, nil
+}
+
+// Size returns how many pixels across each tile is.
+func (m *Map) Size() float64 {
+	return m.size
+}
+
+func (m *Map) Cols() int {
+	if m == nil {
+		return 0
+	}
+	return m.cols
+}
+
+func (m *Map) Rows() int {
+	if m == nil {
+		return 0
+	}
+	return m.rows
+}
+
+// At returns the tile at col, row.
+func (m *Map) At(col, row int) Tile {
+	if m == nil || !m.inside(col, row) {
+		return Floor
+	}
+	return m.tiles[(row*m.cols)+col]
+}
+
+// Cell returns the column and row of the tile p is in.
+func (m *Map) Cell(p geom.Vector) (int, int) {
+	return int(math.Floor(p.X / m.size)), int(math.Floor(p.Y / m.size))
+}
+
+// Center returns the middle of the tile at col, row.
+func (m *Map) Center(col, row int) geom.Vector {
+	return geom.Vector{X: (float64(col) + 0.5) * m.size, Y: (float64(row) + 0.5) * m.size}
+}
+
+// TileRect returns the area the tile at col, row covers.
+func (m *Map) TileRect(col, row int) geom.Rect {
+	min := geom.Vector{X: float64(col) * m.size, Y: float64(row) * m.size}
+	return geom.Rect{Min: min, Max: min.Add(geom.Vector{X: m.size, Y: m.size})}
+}
+
+// IsSolid reports whether p is inside a solid tile.
+func (m *Map) IsSolid(p geom.Vector) bool {
+	if m == nil {
+		return false
+	}
+	return m.At(m.Cell(p)).Solid()
+}
+
+// Overlaps reports whether r overlaps any solid tile. Touching the edge of
+// a tile does not count.
+func (m *Map) Overlaps(r geom.Rect) bool {
+	if m == nil {
+		return false
+	}
+	minCol := int(math.Floor(r.Min.X / m.size))
+	minRow := int(math.Floor(r.Min.Y / m.size))
+	maxCol := int(math.Ceil(r.Max.X/m.size)) - 1
+	maxRow := int(math.Ceil(r.Max.Y/m.size)) - 1
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			if m.At(col, row).Solid() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func box(center geom.Vector, half float64) geom.Rect {
+	return geom.Rect{
+		Min: geom.Vector{X: center.X - half, Y: center.Y - half},
+		Max: geom.Vector{X: center.X + half, Y: center.Y + half},
+	}
+}
+
+// Move returns where a box half pixels either side of from ends up when it
+// tries to move by delta. The box moves along each axis in turn and stops
+// against any solid tile, so it slides along walls it meets at an angle. A
+// box that starts inside a wall moves freely so it can get out.
+func (m *Map) Move(from, delta geom.Vector, half float64) geom.Vector {
+	if m == nil || m.Overlaps(box(from, half)) {
+		return from.Add(delta)
+	}
+	to := from
+	to.X = to.X + delta.X
+	if m.Overlaps(box(to, half)) {
+		if delta.X > 0 {
+			to.X = (math.Ceil((to.X+half)/m.size)-1)*m.size - half
+		} else {
+			to.X = math.Floor((to.X-half)/m.size+1)*m.size + half
+		}
+		if m.Overlaps(box(to, half)) {
+			to.X = from.X
+		}
+	}
+	to.Y = to.Y + delta.Y
+	if m.Overlaps(box(to, half)) {
+		if delta.Y > 0 {
+			to.Y = (math.Ceil((to.Y+half)/m.size)-1)*m.size - half
+		} else {
+			to.Y = math.Floor((to.Y-half)/m.size+1)*m.size + half
+		}
+		if m.Overlaps(box(to, half)) {
+			to.Y = from.Y
+		}
+	}
+	return to
+}
+
+// Segment reports whether the segment from..to touches a solid tile and,
+// if it does, the fraction of the way along the segment where it first
+// does.
+func (m *Map) Segment(from, to geom.Vector) (float64, bool) {
+	if m == nil {
+		return 0, false
+	}
+	minCol, minRow := m.Cell(geom.Vector{X: math.Min(from.X, to.X), Y: math.Min(from.Y, to.Y)})
+	maxCol, maxRow := m.Cell(geom.Vector{X: math.Max(from.X, to.X), Y: math.Max(from.Y, to.Y)})
+	first := math.MaxFloat64
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			if !m.At(col, row).Solid() {
+				continue
+			}
+			if t, ok := geom.SegmentRect(from, to, m.TileRect(col, row)); ok && t < first {
+				first = t
+			}
+		}
+	}
+	return first, first <= 1
+}
+
+// Clear reports whether nothing solid lies on the straight line from..to.
+// It walks only the tiles the line passes through, so it stays cheap enough
+// to ask for every enemy on every update.
+func (m *Map) Clear(from, to geom.Vector) bool {
+	if m == nil {
+		return true
+	}
+	col, row := m.Cell(from)
+	endCol, endRow := m.Cell(to)
+	delta := to.Sub(from)
+	stepCol, nextX, everyX := m.crossings(from.X, delta.X, col)
+	stepRow, nextY, everyY := m.crossings(from.Y, delta.Y, row)
+	for col != endCol || row != endRow {
+		if m.At(col, row).Solid() {
+			return false
+		}
+		// stepping by the nearest crossing, but never past the end tile,
+		// so rounding cannot walk the line off course
+		if row == endRow || (col != endCol && nextX < nextY) {
+			col = col + stepCol
+			nextX = nextX + everyX
+		} else {
+			row = row + stepRow
+			nextY = nextY + everyY
+		}
+	}
+	return !m.At(col, row).Solid()
+}
+
+// crossings returns which way a line starting at start in tile cell and
+// moving delta steps along one axis, the fraction of the line at which it
+// first crosses a tile edge, and the fraction between later crossings.
+func (m *Map) crossings(start, delta float64, cell int) (int, float64, float64) {
+	if delta == 0 {
+		return 0, math.Inf(1), math.Inf(1)
+	}
+	every := m.size / math.Abs(delta)
+	if delta > 0 {
+		return 1, ((float64(cell+1) * m.size) - start) / delta, every
+	}
+	return -1, ((float64(cell) * m.size) - start) / delta, every
+}
+
+// NearestOpen returns p if it is not in a solid tile, or else the middle of
+// the nearest floor tile for which ok is true.
+func (m *Map) NearestOpen(p geom.Vector, ok func(geom.Vector) bool) geom.Vector {
+	if !m.IsSolid(p) {
+		return p
+	}
+	col, row := m.Cell(p)
+	limit := m.cols + m.rows
+	for r := 1; r <= limit; r++ {
+		best := geom.Vector{}
+		found := false
+		for y := row - r; y <= row+r; y++ {
+			for x := col - r; x <= col+r; x++ {
+				if (y != row-r && y != row+r && x != col-r && x != col+r) || m.At(x, y).Solid() {
+					continue
+				}
+				c := m.Center(x, y)
+				if !ok(c) {
+					continue
+				}
+				if !found || c.Sub(p).Length() < best.Sub(p).Length() {
+					best = c
+					found = true
+				}
+			}
+		}
+		if found {
+			return best
+		}
+	}
+	return p
+}
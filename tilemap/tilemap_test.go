@@ -0,0 +1,174 @@
+package tilemap
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/markrzasa/arrowsaway/geom"
+)
+
+// testRows is a small map of 10 pixel tiles: a wall two tiles long across
+// the second row and a rock in the fourth.
+var testRows = []string{
+	"......",
+	".##...",
+	"......",
+	"...o..",
+}
+
+func testMap(t *testing.T) *Map {
+	t.Helper()
+	m, err := Parse(testRows, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func near(a, b geom.Vector) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func TestParse(t *testing.T) {
+	m := testMap(t)
+	if m.Cols() != 6 || m.Rows() != 4 {
+		t.Errorf("map is %dx%d, want 6x4", m.Cols(), m.Rows())
+	}
+	tiles := []struct {
+		col, row int
+		want     Tile
+	}{
+		{0, 0, Floor},
+		{1, 1, Wall},
+		{2, 1, Wall},
+		{3, 3, Rock},
+		{-1, 1, Floor},
+		{6, 3, Floor},
+	}
+	for _, tt := range tiles {
+		if got := m.At(tt.col, tt.row); got != tt.want {
+			t.Errorf("At(%d, %d) = %v, want %v", tt.col, tt.row, got, tt.want)
+		}
+	}
+
+	errors := []struct {
+		name string
+		rows []string
+		size int
+		want string
+	}{
+		{"no size", testRows, 0, "tile size must be positive"},
+		{"unknown tile", []string{"..", ".x"}, 10, "map row 2: unknown tile 'x'"},
+		{"ragged", []string{"...", "..", "..."}, 10, "map row 2 is 2 tiles long, not 3"},
+	}
+	for _, tt := range errors {
+		_, err := Parse(tt.rows, tt.size)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	m := testMap(t)
+	tests := []struct {
+		name  string
+		walls *Map
+		from  geom.Vector
+		delta geom.Vector
+		want  geom.Vector
+	}{
+		{"open floor", m, geom.Vector{X: 5, Y: 5}, geom.Vector{X: 3}, geom.Vector{X: 8, Y: 5}},
+		{"into a wall from the left", m, geom.Vector{X: 5, Y: 15}, geom.Vector{X: 10}, geom.Vector{X: 8, Y: 15}},
+		{"into a wall from the right", m, geom.Vector{X: 35, Y: 15}, geom.Vector{X: -10}, geom.Vector{X: 32, Y: 15}},
+		{"into a wall from above", m, geom.Vector{X: 15, Y: 5}, geom.Vector{Y: 10}, geom.Vector{X: 15, Y: 8}},
+		{"into a wall from below", m, geom.Vector{X: 15, Y: 25}, geom.Vector{Y: -10}, geom.Vector{X: 15, Y: 22}},
+		{"slide along the top of a wall", m, geom.Vector{X: 5, Y: 5}, geom.Vector{X: 4, Y: 4}, geom.Vector{X: 9, Y: 8}},
+		{"slide along the side of a wall", m, geom.Vector{X: 5, Y: 15}, geom.Vector{X: 4, Y: 1}, geom.Vector{X: 8, Y: 16}},
+		{"against a rock", m, geom.Vector{X: 35, Y: 25}, geom.Vector{Y: 5}, geom.Vector{X: 35, Y: 28}},
+		{"starting inside a wall", m, geom.Vector{X: 15, Y: 15}, geom.Vector{X: 3, Y: 3}, geom.Vector{X: 18, Y: 18}},
+		{"no map", nil, geom.Vector{X: 5, Y: 15}, geom.Vector{X: 10}, geom.Vector{X: 15, Y: 15}},
+	}
+	for _, tt := range tests {
+		if got := tt.walls.Move(tt.from, tt.delta, 2); !near(got, tt.want) {
+			t.Errorf("%s: moved to %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	m := testMap(t)
+	tests := []struct {
+		name     string
+		walls    *Map
+		from, to geom.Vector
+		want     float64
+		hit      bool
+	}{
+		{"across open floor", m, geom.Vector{X: 0, Y: 5}, geom.Vector{X: 50, Y: 5}, 0, false},
+		{"short of a wall", m, geom.Vector{X: 0, Y: 15}, geom.Vector{X: 5, Y: 15}, 0, false},
+		{"first of two wall tiles", m, geom.Vector{X: 50, Y: 15}, geom.Vector{X: 0, Y: 15}, 0.4, true},
+		{"wall before rock", m, geom.Vector{X: 0, Y: 0}, geom.Vector{X: 40, Y: 40}, 0.25, true},
+		{"rock before wall", m, geom.Vector{X: 40, Y: 40}, geom.Vector{X: 0, Y: 0}, 0, true},
+		{"no map", nil, geom.Vector{X: 0, Y: 15}, geom.Vector{X: 50, Y: 15}, 0, false},
+	}
+	for _, tt := range tests {
+		got, hit := tt.walls.Segment(tt.from, tt.to)
+		if hit != tt.hit || (hit && math.Abs(got-tt.want) > 1e-9) {
+			t.Errorf("%s: Segment = %v, %v, want %v, %v", tt.name, got, hit, tt.want, tt.hit)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	m := testMap(t)
+	tests := []struct {
+		name     string
+		walls    *Map
+		from, to geom.Vector
+		want     bool
+	}{
+		{"along open floor", m, geom.Vector{X: 5, Y: 5}, geom.Vector{X: 55, Y: 5}, true},
+		{"down the open column", m, geom.Vector{X: 5, Y: 5}, geom.Vector{X: 5, Y: 35}, true},
+		{"within a tile", m, geom.Vector{X: 5, Y: 5}, geom.Vector{X: 6, Y: 6}, true},
+		{"through a wall", m, geom.Vector{X: 5, Y: 15}, geom.Vector{X: 55, Y: 15}, false},
+		{"diagonally through a wall", m, geom.Vector{X: 5, Y: 5}, geom.Vector{X: 25, Y: 25}, false},
+		{"sloping into a rock", m, geom.Vector{X: 5, Y: 25}, geom.Vector{X: 55, Y: 38}, false},
+		{"past the end of the wall", m, geom.Vector{X: 25, Y: 25}, geom.Vector{X: 55, Y: 5}, true},
+		{"ending in a wall", m, geom.Vector{X: 15, Y: 5}, geom.Vector{X: 15, Y: 15}, false},
+		{"no map", nil, geom.Vector{X: 5, Y: 15}, geom.Vector{X: 55, Y: 15}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.walls.Clear(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: Clear = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.walls.Clear(tt.to, tt.from); got != tt.want {
+			t.Errorf("%s backwards: Clear = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNearestOpen(t *testing.T) {
+	m := testMap(t)
+	anywhere := func(geom.Vector) bool { return true }
+	tests := []struct {
+		name  string
+		walls *Map
+		p     geom.Vector
+		ok    func(geom.Vector) bool
+		want  geom.Vector
+	}{
+		{"already open", m, geom.Vector{X: 3, Y: 4}, anywhere, geom.Vector{X: 3, Y: 4}},
+		{"inside a wall", m, geom.Vector{X: 12, Y: 15}, anywhere, geom.Vector{X: 5, Y: 15}},
+		{"inside a rock", m, geom.Vector{X: 36, Y: 32}, anywhere, geom.Vector{X: 35, Y: 25}},
+		{"not to the left", m, geom.Vector{X: 12, Y: 15}, func(p geom.Vector) bool { return p.X > 10 }, geom.Vector{X: 15, Y: 5}},
+		{"nowhere near", m, geom.Vector{X: 12, Y: 15}, func(p geom.Vector) bool { return p.X > 50 }, geom.Vector{X: 55, Y: 15}},
+		{"no map", nil, geom.Vector{X: 12, Y: 15}, anywhere, geom.Vector{X: 12, Y: 15}},
+	}
+	for _, tt := range tests {
+		if got := tt.walls.NearestOpen(tt.p, tt.ok); !near(got, tt.want) {
+			t.Errorf("%s: NearestOpen = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}